@@ -0,0 +1,179 @@
+package achievements
+
+import (
+	"fmt"
+
+	"github.com/rayhanadev/2048/game"
+)
+
+// MoveEvent describes a single successful move
+type MoveEvent struct {
+	Direction game.Direction
+	Result    *game.MoveResult
+	Game      *game.Game
+}
+
+// GameEndEvent describes a finished game along with the player's history
+type GameEndEvent struct {
+	Game          *game.Game
+	GamesPlayed   int
+	CurrentStreak int
+}
+
+// GameState is per-game bookkeeping available to rules
+type GameState struct {
+	DirectionCounts [4]int
+}
+
+// Rule is a single achievement and the condition that unlocks it.
+// Either check may be nil if the rule doesn't react to that event.
+type Rule struct {
+	ID          string
+	Name        string
+	Description string
+	OnMove      func(s *GameState, ev MoveEvent) bool
+	OnGameEnd   func(s *GameState, ev GameEndEvent) bool
+}
+
+// DefaultRules returns the built-in achievements in display order
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			ID:          "first_game",
+			Name:        "Getting Started",
+			Description: "Finish your first game",
+			OnGameEnd: func(s *GameState, ev GameEndEvent) bool {
+				return ev.GamesPlayed >= 1
+			},
+		},
+		ReachTile("first_512", "Halfway There", 512),
+		ReachTile("first_1024", "Almost", 1024),
+		ReachTile("first_2048", "2048!", 2048),
+		ReachTile("first_4096", "Beyond", 4096),
+		{
+			ID:          "no_up_1024",
+			Name:        "Gravity",
+			Description: "Reach 1024 without ever moving up",
+			OnMove: func(s *GameState, ev MoveEvent) bool {
+				return s.DirectionCounts[game.Up] == 0 && ev.Game.MaxTile() >= 1024
+			},
+		},
+		WinWithin("speedrun", "Speedrunner", 1200),
+		{
+			ID:          "score_20000",
+			Name:        "High Roller",
+			Description: "Score 20,000 points in a single game",
+			OnMove: func(s *GameState, ev MoveEvent) bool {
+				return ev.Game.Score >= 20000
+			},
+		},
+		{
+			ID:          "games_100",
+			Name:        "Dedicated",
+			Description: "Finish 100 games",
+			OnGameEnd: func(s *GameState, ev GameEndEvent) bool {
+				return ev.GamesPlayed >= 100
+			},
+		},
+		Streak("streak_10", "Habit", 10),
+	}
+}
+
+// ReachTile unlocks when a tile of at least the given value appears
+func ReachTile(id, name string, tile int) Rule {
+	return Rule{
+		ID:          id,
+		Name:        name,
+		Description: fmt.Sprintf("Reach the %d tile", tile),
+		OnMove: func(s *GameState, ev MoveEvent) bool {
+			return ev.Game.MaxTile() >= tile
+		},
+	}
+}
+
+// WinWithin unlocks when 2048 is reached in fewer than maxMoves moves
+func WinWithin(id, name string, maxMoves int) Rule {
+	return Rule{
+		ID:          id,
+		Name:        name,
+		Description: fmt.Sprintf("Reach 2048 in under %d moves", maxMoves),
+		OnMove: func(s *GameState, ev MoveEvent) bool {
+			return ev.Game.Won && ev.Game.Moves < maxMoves
+		},
+	}
+}
+
+// Streak unlocks when the player has played on the given number of consecutive days
+func Streak(id, name string, days int) Rule {
+	return Rule{
+		ID:          id,
+		Name:        name,
+		Description: fmt.Sprintf("Play on %d days in a row", days),
+		OnGameEnd: func(s *GameState, ev GameEndEvent) bool {
+			return ev.CurrentStreak >= days
+		},
+	}
+}
+
+// Tracker evaluates rules for one player and remembers what is already unlocked
+type Tracker struct {
+	rules    []Rule
+	unlocked map[string]bool
+	state    GameState
+}
+
+// NewTracker creates a tracker that skips the already unlocked achievement IDs
+func NewTracker(rules []Rule, unlocked []string) *Tracker {
+	t := &Tracker{
+		rules:    rules,
+		unlocked: make(map[string]bool, len(unlocked)),
+	}
+	for _, id := range unlocked {
+		t.unlocked[id] = true
+	}
+	return t
+}
+
+// Rules returns every rule the tracker knows about
+func (t *Tracker) Rules() []Rule {
+	return t.rules
+}
+
+// IsUnlocked reports whether an achievement has been unlocked
+func (t *Tracker) IsUnlocked(id string) bool {
+	return t.unlocked[id]
+}
+
+// Move records a successful move and returns any newly unlocked achievements
+func (t *Tracker) Move(ev MoveEvent) []Rule {
+	t.state.DirectionCounts[ev.Direction]++
+	return t.check(func(r Rule) bool {
+		return r.OnMove != nil && r.OnMove(&t.state, ev)
+	})
+}
+
+// GameEnd records a finished game and returns any newly unlocked achievements
+func (t *Tracker) GameEnd(ev GameEndEvent) []Rule {
+	return t.check(func(r Rule) bool {
+		return r.OnGameEnd != nil && r.OnGameEnd(&t.state, ev)
+	})
+}
+
+// NewGame clears per-game state
+func (t *Tracker) NewGame() {
+	t.state = GameState{}
+}
+
+func (t *Tracker) check(matches func(Rule) bool) []Rule {
+	var unlocked []Rule
+	for _, r := range t.rules {
+		if t.unlocked[r.ID] {
+			continue
+		}
+		if matches(r) {
+			t.unlocked[r.ID] = true
+			unlocked = append(unlocked, r)
+		}
+	}
+	return unlocked
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rayhanadev/2048/config"
+	"github.com/rayhanadev/2048/storage"
+)
+
+const banUsage = "usage: ban list [-all] | add [-reason text] [-for duration] <fingerprint|ip|cidr> | remove <id>"
+
+func banCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(banUsage)
+	}
+
+	db, err := openDB(ctx, cfg, dbWrite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("ban list", flag.ContinueOnError)
+		all := fs.Bool("all", false, "include expired bans")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return listBans(ctx, db, *all)
+
+	case "add":
+		return addBan(ctx, db, args[1:])
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: ban remove <id>")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ban ID %q", args[1])
+		}
+		ban, err := db.RemoveBan(ctx, id, operator())
+		if err != nil {
+			return fmt.Errorf("ban %d: %w", id, err)
+		}
+		fmt.Printf("Removed %s ban on %s\n", ban.Kind, ban.Value)
+		return nil
+	}
+
+	return fmt.Errorf("unknown ban command %q", args[0])
+}
+
+func addBan(ctx context.Context, db *storage.DB, args []string) error {
+	fs := flag.NewFlagSet("ban add", flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the ban was added")
+	duration := fs.Duration("for", 0, "how long the ban lasts, e.g. 24h; permanent when unset")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: ban add [-reason text] [-for duration] <fingerprint|ip|cidr>")
+	}
+	if *duration < 0 {
+		return errors.New("-for must be positive")
+	}
+
+	var expiresAt time.Time
+	if *duration > 0 {
+		expiresAt = time.Now().Add(*duration)
+	}
+
+	ban, err := db.AddBan(ctx, banKind(fs.Arg(0)), fs.Arg(0), *reason, expiresAt, operator())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Banned %s %s (#%d) %s\n", ban.Kind, ban.Value, ban.ID, banExpiry(ban))
+	return nil
+}
+
+// banKind guesses what kind of ban a value is for
+func banKind(value string) storage.BanKind {
+	switch {
+	case strings.HasPrefix(value, "SHA256:"):
+		return storage.BanFingerprint
+	case strings.Contains(value, "/"):
+		return storage.BanCIDR
+	}
+	return storage.BanIP
+}
+
+func banExpiry(ban *storage.Ban) string {
+	if !ban.ExpiresAt.Valid {
+		return "permanently"
+	}
+	return "until " + ban.ExpiresAt.Time.Local().Format(time.DateTime)
+}
+
+func listBans(ctx context.Context, db *storage.DB, all bool) error {
+	bans, err := db.GetBans(ctx, all)
+	if err != nil {
+		return err
+	}
+	if len(bans) == 0 {
+		fmt.Println("No bans")
+		return nil
+	}
+
+	now := time.Now()
+	for i := range bans {
+		b := &bans[i]
+		expiry := banExpiry(b)
+		if !b.Active(now) {
+			expiry = "expired " + b.ExpiresAt.Time.Local().Format(time.DateTime)
+		}
+		fmt.Printf("%4d  %-11s %-52s %-30s %s  %s\n", b.ID, b.Kind, b.Value, expiry, b.CreatedBy, b.Reason)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rayhanadev/2048/config"
+	"github.com/rayhanadev/2048/storage"
+)
+
+// cliCommand is an administrative subcommand run on the host, e.g. `2048 merge-players`
+type cliCommand struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, cfg *config.Config, args []string) error
+}
+
+var cliCommands = map[string]cliCommand{
+	"backup": {
+		usage: "backup <file>",
+		help:  "write a consistent snapshot of the database, safe while the server runs",
+		run:   backupCommand,
+	},
+	"ban": {
+		usage: "ban list [-all] | add [-reason text] [-for duration] <target> | remove <id>",
+		help:  "ban a key fingerprint, IP address or CIDR range from connecting",
+		run:   banCommand,
+	},
+	"export": {
+		usage: "export [-format jsonl|csv] <dir>",
+		help:  "export players, keys, scores (one per finished game), history, bans and the audit log",
+		run:   exportCommand,
+	},
+	"import": {
+		usage: "import [-format jsonl|csv] [-on-conflict skip|replace|fail] <dir>",
+		help:  "import a previous export into the configured database",
+		run:   importCommand,
+	},
+	"prune": {
+		usage: "prune [-dry-run] [-days n] [-keep-top n] [-keep-leaderboard n]",
+		help:  "delete scores outside the retention policy, expired pairing codes and guest runs",
+		run:   pruneCommand,
+	},
+	"merge-players": {
+		usage: "merge-players [-username name] [-yes] <keep> <merge>",
+		help:  "merge the second player's history into the first and delete the second",
+		run:   mergePlayersCommand,
+	},
+	"loadtest": {
+		usage: "loadtest [-sessions n] [-games n] [-db file] [-conns n] [-queue n]",
+		help:  "simulate concurrent sessions against a throwaway database and report latency",
+		run:   loadTestCommand,
+	},
+	"season": {
+		usage: "season list | create <name> <start> <end> | archive | standings <name>",
+		help:  "manage leaderboard seasons; times are UTC unless an offset is given",
+		run:   seasonCommand,
+	},
+	"tournament": {
+		usage: "tournament list | create <name> <start> <end> | standings <name> | finalize",
+		help:  "manage seeded tournaments; create takes -rounds, -attempts and -seeds",
+		run:   tournamentCommand,
+	},
+}
+
+func runCLI(ctx context.Context, cfg *config.Config, args []string) error {
+	cmd, ok := cliCommands[args[0]]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd.run(ctx, cfg, args[1:])
+}
+
+func printUsage() {
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: 2048 [command]")
+	fmt.Fprintln(os.Stderr, "\nWith no command the SSH server is started.\n\nCommands:")
+	for _, name := range names {
+		c := cliCommands[name]
+		fmt.Fprintf(os.Stderr, "  %-64s %s\n", c.usage, c.help)
+	}
+}
+
+// operator identifies whoever runs a CLI command in the audit log
+func operator() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
+
+// lookupPlayer finds a player by numeric ID or by username
+func lookupPlayer(ctx context.Context, db *storage.DB, ref string) (*storage.Player, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return db.GetPlayerByID(ctx, id)
+	}
+	return db.GetPlayerByUsername(ctx, ref)
+}
+
+func mergePlayersCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("merge-players", flag.ContinueOnError)
+	username := fs.String("username", "", "username the kept player should end up with")
+	yes := fs.Bool("yes", false, "perform the merge instead of previewing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("usage: merge-players [-username name] [-yes] <keep> <merge>")
+	}
+
+	db, err := openDB(ctx, cfg, dbWrite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	keep, err := lookupPlayer(ctx, db, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	merge, err := lookupPlayer(ctx, db, fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(1), err)
+	}
+
+	result, err := db.MergePlayers(ctx, keep.ID, merge.ID, *username, operator(), !*yes)
+	if err != nil {
+		return err
+	}
+
+	verb := "Would move"
+	if *yes {
+		verb = "Moved"
+	}
+	fmt.Printf("%s %d scores, %d keys, %d achievements and %d renames from %s (#%d) into %s (#%d), username %s\n",
+		verb, result.Scores, result.Keys, result.Achievements, result.Renames,
+		result.Merged.Username, result.Merged.ID, result.Kept.Username, result.Kept.ID, result.Username)
+	if !*yes {
+		fmt.Println("Run again with -yes to merge.")
+	}
+	return nil
+}
+
+func pruneCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be pruned without deleting anything")
+	days := fs.Int("days", cfg.ScoreRetentionDays, "keep every score newer than this many days (0 keeps all scores)")
+	keepTop := fs.Int("keep-top", cfg.KeepTopScores, "always keep this many best scores per player")
+	keepLeaderboard := fs.Int("keep-leaderboard", cfg.KeepLeaderboardScores, "always keep this many best scores overall")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(ctx, cfg, dbWrite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := db.Prune(ctx, storage.RetentionPolicy{
+		MaxAge:           time.Duration(*days) * 24 * time.Hour,
+		KeepTopPerPlayer: *keepTop,
+		KeepLeaderboard:  *keepLeaderboard,
+	}, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	verb := "Pruned"
+	if report.DryRun {
+		verb = "Would prune"
+	}
+	if *days <= 0 {
+		fmt.Println("Score pruning is disabled (set -days or SCORE_RETENTION_DAYS)")
+	} else if report.Scores == 0 {
+		fmt.Println("No scores to prune")
+	} else {
+		fmt.Printf("%s %d scores from %d players, dated %s to %s\n",
+			verb, report.Scores, report.Players,
+			report.OldestScore.Format(time.DateOnly), report.NewestScore.Format(time.DateOnly))
+	}
+	fmt.Printf("%s %d expired pairing codes\n", verb, report.ExpiredPairing)
+	fmt.Printf("%s %d expired guest runs\n", verb, report.ExpiredGuest)
+	return nil
+}
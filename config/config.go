@@ -1,9 +1,12 @@
 package config
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all application configuration
@@ -12,6 +15,63 @@ type Config struct {
 	SSHHost     string
 	DataDir     string
 	HostKeyPath string
+
+	// LogLevel is one of debug, info, warn or error, and LogFormat is text or json
+	LogLevel  string
+	LogFormat string
+
+	// UsernameBlocklistPath points to a file of blocked words, one per line
+	UsernameBlocklistPath string
+	RenameCooldown        time.Duration
+
+	// Scores older than ScoreRetentionDays are pruned unless they are among
+	// the player's KeepTopScores best or the global KeepLeaderboardScores best.
+	// Zero days disables pruning.
+	ScoreRetentionDays    int
+	KeepTopScores         int
+	KeepLeaderboardScores int
+	PruneInterval         time.Duration
+
+	// DBMaxOpenConns caps the connection pool, DBBusyTimeout is how long a
+	// connection waits for a lock and DBWriteQueue is how many writes may wait
+	// for the single writer. A zero DBWriteQueue disables the queue.
+	DBMaxOpenConns int
+	DBBusyTimeout  time.Duration
+	DBWriteQueue   int
+
+	// ConnRatePerIP is how many sessions one address may open per minute,
+	// MaxSessionsPerKey caps the open sessions of one key and MaxSessions
+	// caps them across the server. Zero disables a limit.
+	ConnRatePerIP     int
+	MaxSessionsPerKey int
+	MaxSessions       int
+
+	// Sessions are closed after IdleTimeout without input or MaxSessionDuration
+	// in total, with a countdown shown for the last TimeoutWarning. Zero
+	// disables a timeout.
+	IdleTimeout        time.Duration
+	MaxSessionDuration time.Duration
+	TimeoutWarning     time.Duration
+
+	// DrainTimeout is how long players get to finish up when the server shuts
+	// down. Games still in progress at the end are saved.
+	DrainTimeout time.Duration
+
+	// AdminKeys are the SHA256 fingerprints of keys that may use the admin menu
+	AdminKeys []string
+
+	// AllowlistPath points to an authorized_keys file. When set, only the
+	// keys listed in it may connect.
+	AllowlistPath string
+
+	// GuestAccess lets clients without a key play as guests through password
+	// or keyboard-interactive auth. It is off unless enabled, and guests are
+	// never allowed with an allowlist.
+	GuestAccess bool
+
+	// HTTPAddr is where the HTTP server for /metrics, /healthz and /readyz
+	// listens, e.g. ":9090". Empty disables it.
+	HTTPAddr string
 }
 
 // Load reads configuration from environment variables with sensible defaults
@@ -21,6 +81,29 @@ func Load() *Config {
 		SSHHost:     "0.0.0.0",
 		DataDir:     "./data",
 		HostKeyPath: ".ssh/2048_host_key",
+
+		LogLevel:  "info",
+		LogFormat: "text",
+
+		RenameCooldown: 7 * 24 * time.Hour,
+
+		KeepTopScores:         10,
+		KeepLeaderboardScores: 100,
+		PruneInterval:         24 * time.Hour,
+
+		DBMaxOpenConns: 8,
+		DBBusyTimeout:  5 * time.Second,
+		DBWriteQueue:   256,
+
+		ConnRatePerIP:     20,
+		MaxSessionsPerKey: 3,
+		MaxSessions:       500,
+
+		IdleTimeout:        30 * time.Minute,
+		MaxSessionDuration: 8 * time.Hour,
+		TimeoutWarning:     time.Minute,
+
+		DrainTimeout: 30 * time.Second,
 	}
 
 	if port := os.Getenv("SSH_PORT"); port != "" {
@@ -41,9 +124,169 @@ func Load() *Config {
 		cfg.HostKeyPath = hostKeyPath
 	}
 
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		cfg.LogLevel = level
+	}
+
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		cfg.LogFormat = format
+	}
+
+	if blocklist := os.Getenv("USERNAME_BLOCKLIST"); blocklist != "" {
+		cfg.UsernameBlocklistPath = blocklist
+	}
+
+	if cooldown := os.Getenv("RENAME_COOLDOWN"); cooldown != "" {
+		if d, err := time.ParseDuration(cooldown); err == nil {
+			cfg.RenameCooldown = d
+		}
+	}
+
+	if days := os.Getenv("SCORE_RETENTION_DAYS"); days != "" {
+		if d, err := strconv.Atoi(days); err == nil {
+			cfg.ScoreRetentionDays = d
+		}
+	}
+
+	if keep := os.Getenv("KEEP_TOP_SCORES"); keep != "" {
+		if k, err := strconv.Atoi(keep); err == nil {
+			cfg.KeepTopScores = k
+		}
+	}
+
+	if keep := os.Getenv("KEEP_LEADERBOARD_SCORES"); keep != "" {
+		if k, err := strconv.Atoi(keep); err == nil {
+			cfg.KeepLeaderboardScores = k
+		}
+	}
+
+	if interval := os.Getenv("PRUNE_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil {
+			cfg.PruneInterval = d
+		}
+	}
+
+	if conns := os.Getenv("DB_MAX_OPEN_CONNS"); conns != "" {
+		if n, err := strconv.Atoi(conns); err == nil && n > 0 {
+			cfg.DBMaxOpenConns = n
+		}
+	}
+
+	if timeout := os.Getenv("DB_BUSY_TIMEOUT"); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err == nil {
+			cfg.DBBusyTimeout = d
+		}
+	}
+
+	if queue := os.Getenv("DB_WRITE_QUEUE"); queue != "" {
+		if n, err := strconv.Atoi(queue); err == nil && n >= 0 {
+			cfg.DBWriteQueue = n
+		}
+	}
+
+	if rate := os.Getenv("CONN_RATE_PER_IP"); rate != "" {
+		if n, err := strconv.Atoi(rate); err == nil && n >= 0 {
+			cfg.ConnRatePerIP = n
+		}
+	}
+
+	if perKey := os.Getenv("MAX_SESSIONS_PER_KEY"); perKey != "" {
+		if n, err := strconv.Atoi(perKey); err == nil && n >= 0 {
+			cfg.MaxSessionsPerKey = n
+		}
+	}
+
+	if sessions := os.Getenv("MAX_SESSIONS"); sessions != "" {
+		if n, err := strconv.Atoi(sessions); err == nil && n >= 0 {
+			cfg.MaxSessions = n
+		}
+	}
+
+	if idle := os.Getenv("IDLE_TIMEOUT"); idle != "" {
+		if d, err := time.ParseDuration(idle); err == nil && d >= 0 {
+			cfg.IdleTimeout = d
+		}
+	}
+
+	if maxDuration := os.Getenv("MAX_SESSION_DURATION"); maxDuration != "" {
+		if d, err := time.ParseDuration(maxDuration); err == nil && d >= 0 {
+			cfg.MaxSessionDuration = d
+		}
+	}
+
+	if warning := os.Getenv("TIMEOUT_WARNING"); warning != "" {
+		if d, err := time.ParseDuration(warning); err == nil && d >= 0 {
+			cfg.TimeoutWarning = d
+		}
+	}
+
+	if drain := os.Getenv("DRAIN_TIMEOUT"); drain != "" {
+		if d, err := time.ParseDuration(drain); err == nil && d >= 0 {
+			cfg.DrainTimeout = d
+		}
+	}
+
+	if admins := os.Getenv("ADMIN_KEYS"); admins != "" {
+		for _, key := range strings.Split(admins, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.AdminKeys = append(cfg.AdminKeys, key)
+			}
+		}
+	}
+
+	if allowlist := os.Getenv("ALLOWLIST_FILE"); allowlist != "" {
+		cfg.AllowlistPath = allowlist
+	}
+
+	if guests := os.Getenv("GUEST_ACCESS"); guests != "" {
+		if g, err := strconv.ParseBool(guests); err == nil {
+			cfg.GuestAccess = g
+		}
+	}
+
+	if addr := os.Getenv("HTTP_ADDR"); addr != "" {
+		cfg.HTTPAddr = addr
+	}
+
 	return cfg
 }
 
+// IsAdmin reports whether a key fingerprint belongs to an admin
+func (c *Config) IsAdmin(fingerprint string) bool {
+	for _, key := range c.AdminKeys {
+		if key == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// UsernameBlocklist reads the blocked words file.
+// Blank lines and lines starting with # are ignored.
+func (c *Config) UsernameBlocklist() ([]string, error) {
+	if c.UsernameBlocklistPath == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(c.UsernameBlocklistPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	return words, scanner.Err()
+}
+
 // EnsureDirectories creates necessary directories if they don't exist
 func (c *Config) EnsureDirectories() error {
 	// Create data directory
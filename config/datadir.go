@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// sqliteHeader is the magic string at the start of every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// sqliteSidecars are the suffixes of files SQLite keeps next to a database
+var sqliteSidecars = []string{"-wal", "-shm"}
+
+// PrepareDataDir makes sure the data directory exists and is writable.
+// Older releases stored the database at the DataDir path itself; such a
+// file is moved to DatabasePath. It reports whether a move happened.
+func (c *Config) PrepareDataDir() (bool, error) {
+	migrated, err := c.migrateLegacyDatabase()
+	if err != nil {
+		return false, fmt.Errorf("failed to move database from %s to %s: %w", c.DataDir, c.DatabasePath(), describeFSError(err))
+	}
+
+	if err := c.EnsureDirectories(); err != nil {
+		return migrated, fmt.Errorf("failed to create directories: %w", describeFSError(err))
+	}
+
+	if err := checkWritable(c.DataDir); err != nil {
+		return migrated, fmt.Errorf("data directory %s is not usable: %w", c.DataDir, describeFSError(err))
+	}
+
+	return migrated, nil
+}
+
+// migrateLegacyDatabase moves a database file found at DataDir into the directory.
+// The move goes through a temporary name so an interrupted run can be resumed.
+func (c *Config) migrateLegacyDatabase() (bool, error) {
+	staging := c.DataDir + ".migrating"
+
+	info, err := os.Stat(c.DataDir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if _, err := os.Stat(staging); err != nil {
+			return false, nil
+		}
+		// A previous run was interrupted after moving the file aside
+	case err != nil:
+		return false, err
+	case info.IsDir():
+		if _, err := os.Stat(staging); err != nil {
+			return false, nil
+		}
+		// A previous run was interrupted after creating the directory
+	default:
+		ok, err := isSQLiteFile(c.DataDir)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, fmt.Errorf("%s is a file but not a SQLite database", c.DataDir)
+		}
+		if err := renameWithSidecars(c.DataDir, staging); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := os.Stat(c.DatabasePath()); err == nil {
+		return false, fmt.Errorf("both %s and %s exist; remove one of them", staging, c.DatabasePath())
+	}
+
+	if err := os.MkdirAll(c.DataDir, 0755); err != nil {
+		return false, err
+	}
+	if err := renameWithSidecars(staging, c.DatabasePath()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func isSQLiteFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return bytes.Equal(header, sqliteHeader), nil
+}
+
+// renameWithSidecars renames a database along with any WAL and shared memory files
+func renameWithSidecars(from, to string) error {
+	for _, suffix := range sqliteSidecars {
+		if err := os.Rename(from+suffix, to+suffix); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(from, to)
+}
+
+// checkWritable verifies a file can be created in dir
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// describeFSError adds a hint for the filesystem errors operators hit most often
+func describeFSError(err error) error {
+	switch {
+	case errors.Is(err, syscall.EROFS):
+		return fmt.Errorf("read-only filesystem; point DATA_DIR at a writable location: %w", err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("permission denied; check the owner and mode of the directory or set DATA_DIR: %w", err)
+	}
+	return err
+}
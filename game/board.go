@@ -14,6 +14,9 @@ type Position struct {
 // Board represents the 4x4 game grid
 type Board struct {
 	Grid [BoardSize][BoardSize]int
+
+	// rng places new tiles; nil uses the shared source
+	rng *rand.Rand
 }
 
 // NewBoard creates an empty board
@@ -21,6 +24,12 @@ func NewBoard() *Board {
 	return &Board{}
 }
 
+// NewSeededBoard creates an empty board whose tiles are placed by rng,
+// so the same moves always produce the same game
+func NewSeededBoard(rng *rand.Rand) *Board {
+	return &Board{rng: rng}
+}
+
 // GetEmptyCells returns all positions with value 0
 func (b *Board) GetEmptyCells() []Position {
 	var empty []Position
@@ -40,10 +49,15 @@ func (b *Board) SpawnTile() *Position {
 		return nil
 	}
 
-	pos := empty[rand.Intn(len(empty))]
+	intn, float := rand.Intn, rand.Float64
+	if b.rng != nil {
+		intn, float = b.rng.Intn, b.rng.Float64
+	}
+
+	pos := empty[intn(len(empty))]
 
 	value := 2
-	if rand.Float64() < 0.1 {
+	if float() < 0.1 {
 		value = 4
 	}
 
@@ -1,5 +1,10 @@
 package game
 
+import (
+	"math/rand"
+	"time"
+)
+
 type Direction int
 
 const (
@@ -29,8 +34,13 @@ type Game struct {
 	Board     *Board
 	Score     int
 	BestScore int
+	Moves     int
+	StartedAt time.Time
 	GameOver  bool
 	Won       bool
+
+	// Seed makes tile placement deterministic when non-zero
+	Seed int64
 }
 
 func NewGame(bestScore int) *Game {
@@ -38,6 +48,7 @@ func NewGame(bestScore int) *Game {
 		Board:     NewBoard(),
 		Score:     0,
 		BestScore: bestScore,
+		StartedAt: time.Now(),
 		GameOver:  false,
 		Won:       false,
 	}
@@ -48,6 +59,15 @@ func NewGame(bestScore int) *Game {
 	return g
 }
 
+// NewSeededGame starts a game whose tiles are placed from seed. Two games
+// with the same seed and the same moves end up identical.
+func NewSeededGame(seed int64, bestScore int) *Game {
+	g := NewGame(bestScore)
+	g.Seed = seed
+	g.Reset()
+	return g
+}
+
 func (g *Game) Move(dir Direction) *MoveResult {
 	if g.GameOver {
 		return nil
@@ -74,6 +94,7 @@ func (g *Game) Move(dir Direction) *MoveResult {
 
 	result.Moved = true
 	result.BoardBefore = boardBefore
+	g.Moves++
 	g.Score += result.Score
 	if g.Score > g.BestScore {
 		g.BestScore = g.Score
@@ -314,11 +335,57 @@ func (g *Game) MaxTile() int {
 	return g.Board.MaxTile()
 }
 
+// Duration returns how long the current game has been played
+func (g *Game) Duration() time.Duration {
+	return time.Since(g.StartedAt)
+}
+
+// Reset starts a new game. Seeded games restart from the beginning of their seed.
 func (g *Game) Reset() {
 	g.Board = NewBoard()
+	if g.Seed != 0 {
+		g.Board = NewSeededBoard(rand.New(rand.NewSource(g.Seed)))
+	}
 	g.Score = 0
+	g.Moves = 0
+	g.StartedAt = time.Now()
 	g.GameOver = false
 	g.Won = false
 	g.Board.SpawnTile()
 	g.Board.SpawnTile()
 }
+
+// Snapshot is the state needed to resume an unfinished game
+type Snapshot struct {
+	Grid   [BoardSize][BoardSize]int `json:"grid"`
+	Score  int                       `json:"score"`
+	Moves  int                       `json:"moves"`
+	Won    bool                      `json:"won"`
+	Played time.Duration             `json:"played"`
+}
+
+// Snapshot captures the game so it can be resumed with RestoreGame
+func (g *Game) Snapshot() Snapshot {
+	return Snapshot{
+		Grid:   g.Board.Grid,
+		Score:  g.Score,
+		Moves:  g.Moves,
+		Won:    g.Won,
+		Played: g.Duration(),
+	}
+}
+
+// RestoreGame resumes a game from a snapshot. Time already played counts
+// towards its duration.
+func RestoreGame(s Snapshot, bestScore int) *Game {
+	g := &Game{
+		Board:     NewBoard(),
+		Score:     s.Score,
+		BestScore: max(bestScore, s.Score),
+		Moves:     s.Moves,
+		StartedAt: time.Now().Add(-s.Played),
+		Won:       s.Won,
+	}
+	g.Board.Grid = s.Grid
+	return g
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rayhanadev/2048/config"
+	"github.com/rayhanadev/2048/game"
+	"github.com/rayhanadev/2048/storage"
+)
+
+// loadTestMaxMoves stops a simulated game that random moves haven't ended
+const loadTestMaxMoves = 2000
+
+// latencies collects timings for one kind of database operation
+type latencies struct {
+	mu     sync.Mutex
+	times  []time.Duration
+	errors int
+	busy   int
+}
+
+func (l *latencies) record(start time.Time, err error) {
+	elapsed := time.Since(start)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.times = append(l.times, elapsed)
+	if err != nil {
+		l.errors++
+		if storage.IsBusy(err) {
+			l.busy++
+		}
+	}
+}
+
+func (l *latencies) report(name string, total time.Duration) {
+	sort.Slice(l.times, func(i, j int) bool { return l.times[i] < l.times[j] })
+	pct := func(p float64) time.Duration {
+		if len(l.times) == 0 {
+			return 0
+		}
+		return l.times[int(float64(len(l.times)-1)*p)]
+	}
+	fmt.Printf("%-7s %7d ops %8.0f/s  p50 %-9v p95 %-9v p99 %-9v max %-9v errors %d (busy %d)\n",
+		name, len(l.times), float64(len(l.times))/total.Seconds(),
+		pct(0.50).Round(time.Microsecond), pct(0.95).Round(time.Microsecond),
+		pct(0.99).Round(time.Microsecond), pct(1).Round(time.Microsecond),
+		l.errors, l.busy)
+}
+
+func loadTestCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	sessions := fs.Int("sessions", 300, "number of concurrent simulated sessions")
+	games := fs.Int("games", 5, "games played by each session")
+	think := fs.Duration("think", 200*time.Millisecond, "longest random pause before each game, like a player reading the board")
+	dbPath := fs.String("db", "", "database to load (defaults to a throwaway file; never point this at production)")
+	conns := fs.Int("conns", cfg.DBMaxOpenConns, "connection pool size")
+	busyTimeout := fs.Duration("busy-timeout", cfg.DBBusyTimeout, "how long a connection waits for a lock")
+	queue := fs.Int("queue", cfg.DBWriteQueue, "write queue size, 0 to write straight to the pool")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *sessions <= 0 || *games <= 0 {
+		return errors.New("usage: loadtest [-sessions n] [-games n] [-think d] [-db file] [-conns n] [-busy-timeout d] [-queue n]")
+	}
+
+	path := *dbPath
+	if path == "" {
+		dir, err := os.MkdirTemp("", "2048-loadtest-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, "loadtest.db")
+	}
+
+	db, err := storage.NewDB(ctx, path, storage.Options{
+		MaxOpenConns:   *conns,
+		BusyTimeout:    *busyTimeout,
+		WriteQueueSize: *queue,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fmt.Printf("Simulating %d sessions × %d games (pool %d, busy timeout %v, write queue %d)\n",
+		*sessions, *games, *conns, *busyTimeout, *queue)
+
+	var reads, writes latencies
+	var failed sync.Map
+	var wg sync.WaitGroup
+	startLine := make(chan struct{})
+	runID := time.Now().UnixNano()
+
+	for i := 0; i < *sessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-startLine
+			if err := simulateSession(ctx, db, fmt.Sprintf("lt%d-%d", runID%100000, i), *games, *think, &reads, &writes); err != nil {
+				failed.Store(i, err)
+			}
+		}(i)
+	}
+
+	start := time.Now()
+	close(startLine)
+	wg.Wait()
+	total := time.Since(start)
+
+	fmt.Printf("Finished in %v\n", total.Round(time.Millisecond))
+	writes.report("writes", total)
+	reads.report("reads", total)
+
+	aborted := 0
+	failed.Range(func(_, v any) bool {
+		if aborted == 0 {
+			fmt.Println("First session failure:", v)
+		}
+		aborted++
+		return true
+	})
+	if aborted > 0 {
+		return fmt.Errorf("%d of %d sessions could not start", aborted, *sessions)
+	}
+	return nil
+}
+
+// simulateSession mirrors the queries a real SSH session makes: signing up,
+// looking up the player, then playing games with leaderboard and stats views
+func simulateSession(ctx context.Context, db *storage.DB, username string, games int, think time.Duration, reads, writes *latencies) error {
+	fingerprint := "SHA256:" + username
+
+	start := time.Now()
+	player, err := db.CreatePlayer(ctx, fingerprint, username)
+	writes.record(start, err)
+	if err != nil {
+		return err
+	}
+
+	start = time.Now()
+	_, err = db.GetPlayerByFingerprint(ctx, fingerprint)
+	reads.record(start, err)
+
+	start = time.Now()
+	writes.record(start, db.TouchKey(ctx, fingerprint))
+
+	start = time.Now()
+	best, err := db.GetPlayerBestScore(ctx, player.ID)
+	reads.record(start, err)
+
+	for i := 0; i < games; i++ {
+		if think > 0 {
+			time.Sleep(time.Duration(rand.Int63n(int64(think))))
+		}
+
+		g := game.NewGame(best)
+		for !g.GameOver && g.Moves < loadTestMaxMoves {
+			g.Move(game.Direction(rand.Intn(4)))
+		}
+
+		if g.MaxTile() >= 128 {
+			start = time.Now()
+			writes.record(start, db.UnlockAchievement(ctx, player.ID, "first_128"))
+		}
+
+		start = time.Now()
+		writes.record(start, db.SaveScore(ctx, player.ID, g.Score, g.MaxTile(), g.Moves, g.Duration()))
+		if g.Score > best {
+			best = g.Score
+		}
+
+		start = time.Now()
+		_, err = db.GetLeaderboard(ctx, nil, 10)
+		reads.record(start, err)
+
+		start = time.Now()
+		_, err = db.GetPlayerStats(ctx, player.ID, 20)
+		reads.record(start, err)
+	}
+
+	return nil
+}
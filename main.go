@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/charmbracelet/log"
 
@@ -14,17 +19,35 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Administrative subcommands run instead of the server
+	if len(os.Args) > 1 {
+		// Ctrl-C cancels whatever the command is waiting on
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runCLI(ctx, cfg, os.Args[1:])
+		stop()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Set up logging
-	log.SetLevel(log.InfoLevel)
+	if err := configureLogging(cfg); err != nil {
+		log.Fatal("Invalid logging configuration", "error", err)
+	}
 	log.Info("SSH 2048 Server starting...")
 	log.Info("Configuration loaded",
 		"port", cfg.SSHPort,
 		"host", cfg.SSHHost,
 		"data_dir", cfg.DataDir,
+		"database", cfg.DatabasePath(),
+		"log_level", cfg.LogLevel,
+		"log_format", cfg.LogFormat,
 	)
 
 	// Initialize database
-	db, err := storage.NewDB(cfg.DataDir)
+	db, err := openDB(context.Background(), cfg, dbMigrate)
 	if err != nil {
 		log.Fatal("Failed to initialize database", "error", err)
 		os.Exit(1)
@@ -47,3 +70,88 @@ func main() {
 
 	log.Info("Server shutdown complete")
 }
+
+// configureLogging applies the configured log level and format
+func configureLogging(cfg *config.Config) error {
+	level, err := log.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+
+	switch strings.ToLower(cfg.LogFormat) {
+	case "text":
+		log.SetFormatter(log.TextFormatter)
+	case "json":
+		log.SetFormatter(log.JSONFormatter)
+	default:
+		return fmt.Errorf("unknown log format %q, use text or json", cfg.LogFormat)
+	}
+	return nil
+}
+
+// dbAccess is how a command uses the database, which decides whether it
+// may migrate the schema or needs it current
+type dbAccess int
+
+const (
+	// dbMigrate brings the schema up to date, which only the server does
+	dbMigrate dbAccess = iota
+	// dbRead opens the database as it is, for commands that only read it
+	dbRead
+	// dbWrite opens the database as it is and refuses any schema but this build's
+	dbWrite
+)
+
+// openDB prepares the data directory, opens the database and applies configured policies
+func openDB(ctx context.Context, cfg *config.Config, access dbAccess) (*storage.DB, error) {
+	migrated, err := cfg.PrepareDataDir()
+	if err != nil {
+		return nil, err
+	}
+	if migrated {
+		log.Info("Moved database to its new location", "path", cfg.DatabasePath())
+	}
+
+	db, err := storage.NewDB(ctx, cfg.DatabasePath(), storage.Options{
+		MaxOpenConns:   cfg.DBMaxOpenConns,
+		BusyTimeout:    cfg.DBBusyTimeout,
+		WriteQueueSize: cfg.DBWriteQueue,
+		SkipMigrations: access != dbMigrate,
+	})
+	if err != nil {
+		return nil, describeDBError(cfg.DatabasePath(), err)
+	}
+
+	// A CLI from another build must not change data laid out differently
+	if access == dbWrite {
+		if err := db.CheckSchema(ctx); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%w; start this build's server once to migrate it, or run the command with the server's binary", err)
+		}
+	}
+
+	blocklist, err := cfg.UsernameBlocklist()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load username blocklist: %w", err)
+	}
+	db.SetUsernamePolicy(storage.UsernamePolicy{
+		Blocklist:      blocklist,
+		RenameCooldown: cfg.RenameCooldown,
+	})
+
+	return db, nil
+}
+
+// describeDBError explains failures to open the database file itself
+func describeDBError(path string, err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "readonly"), strings.Contains(msg, "read-only"):
+		return fmt.Errorf("database %s is read-only; check file permissions and that the filesystem is writable: %w", path, err)
+	case strings.Contains(msg, "unable to open"):
+		return fmt.Errorf("cannot open database %s; check that the server user can read and write it: %w", path, err)
+	}
+	return err
+}
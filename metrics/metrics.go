@@ -0,0 +1,108 @@
+// Package metrics defines the Prometheus metrics exported on /metrics
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric; Prometheus names can't start with a digit
+const namespace = "ssh2048"
+
+// Game modes used as the mode label
+const (
+	ModeNormal     = "normal"
+	ModeGuest      = "guest"
+	ModeTournament = "tournament"
+)
+
+var (
+	// Connections counts accepted TCP connections, before authentication
+	Connections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connections_total",
+		Help:      "TCP connections accepted, before authentication.",
+	})
+
+	// ActiveSessions is the number of open SSH sessions
+	ActiveSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Open SSH sessions.",
+	})
+
+	// AuthRejections counts connections and credentials refused during
+	// authentication, by reason
+	AuthRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_rejections_total",
+		Help:      "Connections and credentials refused before a session started, by reason.",
+	}, []string{"reason"})
+
+	// SessionRejections counts authenticated sessions refused by a limit or ban, by reason
+	SessionRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "session_rejections_total",
+		Help:      "Authenticated sessions refused by a limit or ban, by reason.",
+	}, []string{"reason"})
+
+	// GamesStarted counts games in which a first move was made
+	GamesStarted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_started_total",
+		Help:      "Games in which the first move was made, by mode.",
+	}, []string{"mode"})
+
+	// GamesFinished counts games that ended with no moves left
+	GamesFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "games_finished_total",
+		Help:      "Games that ended with no moves left, by mode.",
+	}, []string{"mode"})
+
+	// Moves counts moves that changed the board
+	Moves = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "moves_total",
+		Help:      "Moves that changed the board.",
+	})
+
+	// FinalScores is the distribution of scores of finished games
+	FinalScores = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "final_score",
+		Help:      "Scores of finished games, by mode.",
+		Buckets:   prometheus.ExponentialBuckets(256, 2, 10),
+	}, []string{"mode"})
+
+	// MaxTiles is the distribution of the largest tile of finished games
+	MaxTiles = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "max_tile",
+		Help:      "Largest tile of finished games, by mode.",
+		Buckets:   prometheus.ExponentialBuckets(32, 2, 10),
+	}, []string{"mode"})
+
+	// DBQueryDuration is the latency of SQLite statements, by kind ("query" or "exec")
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of SQLite statements, by kind.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"kind"})
+
+	// DBErrors counts SQLite statements that failed, by kind
+	DBErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_errors_total",
+		Help:      "SQLite statements that failed, by kind.",
+	}, []string{"kind"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
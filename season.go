@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rayhanadev/2048/config"
+	"github.com/rayhanadev/2048/storage"
+)
+
+// seasonTimeLayouts are the accepted formats for season start and end times.
+// Times without an offset are UTC.
+var seasonTimeLayouts = []string{time.DateOnly, "2006-01-02 15:04", time.RFC3339}
+
+func seasonCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: season list | create <name> <start> <end> | archive | standings <name>")
+	}
+
+	db, err := openDB(ctx, cfg, dbWrite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		return listSeasons(ctx, db)
+
+	case "create":
+		if len(args) != 4 {
+			return errors.New("usage: season create <name> <start> <end>")
+		}
+		start, err := parseSeasonTime(args[2])
+		if err != nil {
+			return err
+		}
+		end, err := parseSeasonTime(args[3])
+		if err != nil {
+			return err
+		}
+		season, err := db.CreateSeason(ctx, args[1], start, end, operator())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created season %s (#%d) from %s to %s\n", season.Name, season.ID,
+			season.StartsAt.Format(time.DateTime), season.EndsAt.Format(time.DateTime))
+		return nil
+
+	case "archive":
+		archived, err := db.ArchiveDueSeasons(ctx, operator())
+		if err != nil {
+			return err
+		}
+		if len(archived) == 0 {
+			fmt.Println("No ended seasons waiting to be archived")
+		}
+		for _, s := range archived {
+			fmt.Printf("Archived season %s\n", s.Name)
+		}
+		return nil
+
+	case "standings":
+		if len(args) != 2 {
+			return errors.New("usage: season standings <name>")
+		}
+		season, err := db.GetSeasonByName(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[1], err)
+		}
+		entries, err := db.GetLeaderboard(ctx, season, storage.SeasonArchiveSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			fmt.Printf("%4d  %-20s %8d %6d\n", e.Rank, e.Username, e.Score, e.MaxTile)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown season command %q", args[0])
+}
+
+func listSeasons(ctx context.Context, db *storage.DB) error {
+	seasons, err := db.GetAllSeasons(ctx)
+	if err != nil {
+		return err
+	}
+	if len(seasons) == 0 {
+		fmt.Println("No seasons")
+		return nil
+	}
+
+	now := time.Now()
+	for _, s := range seasons {
+		status := "upcoming"
+		switch {
+		case s.Archived():
+			status = "archived"
+		case s.Active(now):
+			status = "active"
+		case !now.Before(s.EndsAt):
+			status = "ended"
+		}
+		fmt.Printf("%4d  %-20s %s  %s  %s\n", s.ID, s.Name,
+			s.StartsAt.Format(time.DateTime), s.EndsAt.Format(time.DateTime), status)
+	}
+	return nil
+}
+
+func parseSeasonTime(value string) (time.Time, error) {
+	for _, layout := range seasonTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use YYYY-MM-DD, \"YYYY-MM-DD HH:MM\" or RFC 3339", value)
+}
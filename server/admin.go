@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/rayhanadev/2048/ui"
+)
+
+// adminTools gives admin sessions access to the server's live sessions
+type adminTools struct {
+	s *Server
+}
+
+// Sessions lists the connected sessions, oldest first
+func (a adminTools) Sessions() []ui.SessionInfo {
+	sessions := a.s.sessions.list()
+	infos := make([]ui.SessionInfo, len(sessions))
+	for i, live := range sessions {
+		infos[i] = ui.SessionInfo{
+			ID:          live.ID,
+			Fingerprint: live.Fingerprint,
+			RemoteIP:    live.RemoteIP,
+			StartedAt:   live.StartedAt,
+			LastActive:  live.idleSince(),
+		}
+	}
+	return infos
+}
+
+// Broadcast shows text to every connected player
+func (a adminTools) Broadcast(text string) int {
+	var sent int
+	for _, live := range a.s.sessions.list() {
+		// Send blocks until the program reads the message, so a slow
+		// session doesn't hold up the rest
+		go live.send(ui.BroadcastMsg{Text: text})
+		sent++
+	}
+	log.Info("Broadcast sent", "recipients", sent)
+	return sent
+}
+
+// DisconnectPlayer closes every session using one of the player's keys
+func (a adminTools) DisconnectPlayer(ctx context.Context, playerID int64) (int, error) {
+	keys, err := a.s.db.GetPlayerKeys(ctx, playerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get player keys: %w", err)
+	}
+
+	fingerprints := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		fingerprints[k.Fingerprint] = true
+	}
+
+	var closed int
+	for _, live := range a.s.sessions.list() {
+		if fingerprints[live.Fingerprint] {
+			live.session.Close()
+			closed++
+		}
+	}
+	log.FromContext(ctx).Info("Disconnected player", "player_id", playerID, "sessions", closed)
+	return closed, nil
+}
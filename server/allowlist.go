@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// allowlist holds the fingerprints of the keys in an authorized_keys file.
+// The file is read again whenever it changes, so keys can be added or
+// removed without a restart.
+type allowlist struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	keys    map[string]bool
+}
+
+// newAllowlist reads an authorized_keys file, failing if it can't be read
+func newAllowlist(path string) (*allowlist, error) {
+	a := &allowlist{path: path}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// allows reports whether a key fingerprint is listed. If the file has become
+// unreadable, the keys last read from it stay in effect.
+func (a *allowlist) allows(fingerprint string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.reload(); err != nil {
+		log.Error("Failed to reload allowlist", "path", a.path, "error", err)
+	}
+	return a.keys[fingerprint]
+}
+
+// reload reads the file again if it changed since it was last read.
+// Callers other than newAllowlist must hold mu.
+func (a *allowlist) reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat allowlist: %w", err)
+	}
+	if a.keys != nil && info.ModTime().Equal(a.modTime) && info.Size() == a.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return fmt.Errorf("failed to read allowlist: %w", err)
+	}
+
+	keys := make(map[string]bool)
+	for line, rest := 1, data; len(bytes.TrimSpace(rest)) > 0; line++ {
+		var text []byte
+		text, rest, _ = bytes.Cut(rest, []byte("\n"))
+		text = bytes.TrimSpace(text)
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		key, _, _, _, err := gossh.ParseAuthorizedKey(text)
+		if err != nil {
+			log.Warn("Skipping invalid allowlist entry", "path", a.path, "line", line, "error", err)
+			continue
+		}
+		keys[gossh.FingerprintSHA256(key)] = true
+	}
+
+	a.keys = keys
+	a.modTime = info.ModTime()
+	a.size = info.Size()
+	log.Info("Loaded allowlist", "path", a.path, "keys", len(keys))
+	return nil
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+
+	"github.com/rayhanadev/2048/metrics"
+	"github.com/rayhanadev/2048/storage"
+)
+
+// connCallback drops connections from banned addresses before the SSH
+// handshake starts
+func (s *Server) connCallback(ctx ssh.Context, conn net.Conn) net.Conn {
+	metrics.Connections.Inc()
+	if s.db == nil {
+		return conn
+	}
+
+	addr, err := netip.ParseAddr(remoteIP(conn.RemoteAddr()))
+	if err != nil {
+		return conn
+	}
+
+	ban, err := s.db.FindAddressBan(ctx, addr)
+	switch {
+	case errors.Is(err, storage.ErrBanNotFound):
+		return conn
+	case err != nil:
+		log.Error("Failed to check address ban", "ip", addr, "error", err)
+		return conn
+	}
+
+	metrics.AuthRejections.WithLabelValues("banned_ip").Inc()
+	log.Warn("Rejected connection", "reason", "banned", "ip", addr, "ban", ban.ID, "ban_reason", ban.Reason)
+	return nil
+}
+
+// allowKey reports whether a key may authenticate: it must not be banned,
+// and must be on the allowlist when one is configured
+func (s *Server) allowKey(ctx ssh.Context, fingerprint string) bool {
+	if s.allowlist != nil && !s.allowlist.allows(fingerprint) {
+		ctx.SetValue(refusedKeyKey{}, true)
+		metrics.AuthRejections.WithLabelValues("not_allowlisted").Inc()
+		log.Warn("Rejected key", "reason", "not_allowlisted", "ip", remoteIP(ctx.RemoteAddr()), "fingerprint", fingerprint)
+		return false
+	}
+
+	if s.db == nil {
+		return true
+	}
+	ban, err := s.db.FindKeyBan(ctx, fingerprint)
+	switch {
+	case errors.Is(err, storage.ErrBanNotFound):
+		return true
+	case err != nil:
+		log.Error("Failed to check key ban", "fingerprint", fingerprint, "error", err)
+		return true
+	}
+
+	ctx.SetValue(refusedKeyKey{}, true)
+	metrics.AuthRejections.WithLabelValues("banned_key").Inc()
+	log.Warn("Rejected key", "reason", "banned", "ip", remoteIP(ctx.RemoteAddr()), "fingerprint", fingerprint,
+		"ban", ban.ID, "ban_reason", ban.Reason)
+	return false
+}
+
+// banMiddleware refuses sessions from keys belonging to banned players
+func (s *Server) banMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			if s.db == nil || isGuest(sess) {
+				next(sess)
+				return
+			}
+
+			fingerprint := s.getFingerprint(sess)
+			player, err := s.db.GetPlayerByFingerprint(sess.Context(), fingerprint)
+			if err != nil && !errors.Is(err, storage.ErrPlayerNotFound) {
+				log.FromContext(sess.Context()).Error("Failed to check ban", "fingerprint", fingerprint, "error", err)
+			}
+			if err == nil && player.BannedAt.Valid {
+				message := "This account has been banned."
+				if player.BanReason != "" {
+					message = fmt.Sprintf("This account has been banned: %s", player.BanReason)
+				}
+				s.reject(sess, "banned", remoteIP(sess.RemoteAddr()), fingerprint, message)
+				return
+			}
+
+			next(sess)
+		}
+	}
+}
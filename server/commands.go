@@ -0,0 +1,480 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+// command is a non-interactive action run via `ssh host <name> [args...]`
+type command struct {
+	usage string
+	help  string
+	run   func(s *Server, sess ssh.Session, args []string) error
+}
+
+var commands = map[string]command{
+	"claim": {
+		usage: "claim <code>",
+		help:  "add a game you finished as a guest to your account",
+		run:   (*Server).claimCommand,
+	},
+	"keys": {
+		usage: "keys [pair | revoke <fingerprint>]",
+		help:  "list, pair or revoke the SSH keys linked to your account",
+		run:   (*Server).keysCommand,
+	},
+	"link": {
+		usage: "link <code>",
+		help:  "link this key to the account that created the pairing code",
+		run:   (*Server).linkCommand,
+	},
+	"merge": {
+		usage: "merge [-username name] [-confirm] <code>",
+		help:  "merge this account into the account that created the pairing code",
+		run:   (*Server).mergeCommand,
+	},
+	"team": {
+		usage: "team [create <name> | join <name> | leave | list | leaderboard [-average]]",
+		help:  "show, create, join or leave a team, or list teams and team standings",
+		run:   (*Server).teamCommand,
+	},
+	"privacy": {
+		usage: "privacy [show | hide]",
+		help:  "show or hide your scores on the public leaderboard",
+		run:   (*Server).privacyCommand,
+	},
+	"export-data": {
+		usage: "export-data",
+		help:  "print everything stored about your account as JSON",
+		run:   (*Server).exportDataCommand,
+	},
+	"delete-account": {
+		usage: "delete-account [-confirm] <username>",
+		help:  "permanently delete your account and all of its data",
+		run:   (*Server).deleteAccountCommand,
+	},
+}
+
+// errNoAccount is returned by commands that need an existing player
+var errNoAccount = errors.New("no account is linked to this key; connect without a command to create one")
+
+// commandMiddleware handles exec requests before the TUI is started
+func (s *Server) commandMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			args := sess.Command()
+			if len(args) == 0 {
+				next(sess)
+				return
+			}
+
+			cmd, ok := commands[args[0]]
+			if !ok {
+				s.printHelp(sess)
+				sess.Exit(1)
+				return
+			}
+
+			if isGuest(sess) {
+				wish.Errorln(sess, "error:", errGuestCommand)
+				sess.Exit(1)
+				return
+			}
+
+			log.FromContext(sess.Context()).Info("Running command", "command", args[0], "remote", sess.RemoteAddr().String())
+			if err := cmd.run(s, sess, args[1:]); err != nil {
+				wish.Errorln(sess, "error:", err)
+				sess.Exit(1)
+				return
+			}
+			sess.Exit(0)
+		}
+	}
+}
+
+func (s *Server) printHelp(sess ssh.Session) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	wish.Errorln(sess, "Available commands:")
+	for _, name := range names {
+		c := commands[name]
+		wish.Errorf(sess, "  %-36s %s\n", c.usage, c.help)
+	}
+}
+
+// sessionPlayer returns the player owning the session's key
+func (s *Server) sessionPlayer(sess ssh.Session) (*storage.Player, error) {
+	player, err := s.db.GetPlayerByFingerprint(sess.Context(), s.getFingerprint(sess))
+	if errors.Is(err, storage.ErrPlayerNotFound) {
+		return nil, errNoAccount
+	}
+	return player, err
+}
+
+func (s *Server) keysCommand(sess ssh.Session, args []string) error {
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		keys, err := s.db.GetPlayerKeys(sess.Context(), player.ID)
+		if err != nil {
+			return err
+		}
+		current := s.getFingerprint(sess)
+		for _, k := range keys {
+			lastUsed := "never"
+			if k.LastUsedAt.Valid {
+				lastUsed = k.LastUsedAt.Time.Format(time.DateTime)
+			}
+			marker := " "
+			if k.Fingerprint == current {
+				marker = "*"
+			}
+			wish.Printf(sess, "%s %s  added %s  last used %s\n", marker, k.Fingerprint, k.CreatedAt.Format(time.DateOnly), lastUsed)
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "pair":
+		code, expiresAt, err := s.db.CreatePairingCode(sess.Context(), player.ID)
+		if err != nil {
+			return err
+		}
+		wish.Printf(sess, "Pairing code: %s (expires %s)\n", code, expiresAt.Local().Format(time.Kitchen))
+		wish.Printf(sess, "From your other key, run: ssh -p %d <host> link %s\n", s.config.SSHPort, code)
+		return nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New("usage: keys revoke <fingerprint>")
+		}
+		fingerprint, err := s.matchKey(sess.Context(), player.ID, args[1])
+		if err != nil {
+			return err
+		}
+		if fingerprint == s.getFingerprint(sess) {
+			return errors.New("connect with a different key to revoke the one you're using")
+		}
+		if err := s.db.RevokeKey(sess.Context(), player.ID, fingerprint); err != nil {
+			return err
+		}
+		wish.Println(sess, "Revoked", fingerprint)
+		return nil
+	}
+
+	return errors.New("usage: keys [pair | revoke <fingerprint>]")
+}
+
+// matchKey resolves a fingerprint or unique fingerprint prefix to one of the player's keys
+func (s *Server) matchKey(ctx context.Context, playerID int64, prefix string) (string, error) {
+	keys, err := s.db.GetPlayerKeys(ctx, playerID)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, k := range keys {
+		if strings.HasPrefix(k.Fingerprint, prefix) || strings.HasPrefix(strings.TrimPrefix(k.Fingerprint, "SHA256:"), prefix) {
+			matches = append(matches, k.Fingerprint)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", storage.ErrKeyNotFound
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q matches %d keys, please be more specific", prefix, len(matches))
+}
+
+func (s *Server) linkCommand(sess ssh.Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: link <code>")
+	}
+
+	player, err := s.db.LinkKey(sess.Context(), args[0], s.getFingerprint(sess))
+	if err != nil {
+		return err
+	}
+
+	wish.Printf(sess, "This key is now linked to %s\n", player.Username)
+	return nil
+}
+
+func (s *Server) mergeCommand(sess ssh.Session, args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.SetOutput(sess.Stderr())
+	username := fs.String("username", "", "username to keep (defaults to the other account's)")
+	confirm := fs.Bool("confirm", false, "perform the merge instead of previewing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: merge [-username name] [-confirm] <code>")
+	}
+
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.db.PairingCodeOwner(sess.Context(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	actor := fmt.Sprintf("player:%d", player.ID)
+	result, err := s.db.MergePlayers(sess.Context(), target.ID, player.ID, *username, actor, !*confirm)
+	if err != nil {
+		return err
+	}
+
+	verb := "Would move"
+	if *confirm {
+		verb = "Moved"
+	}
+	wish.Printf(sess, "%s %d scores, %d keys and %d achievements from %s into %s (username: %s)\n",
+		verb, result.Scores, result.Keys, result.Achievements, result.Merged.Username, result.Kept.Username, result.Username)
+	if !*confirm {
+		wish.Println(sess, "Run again with -confirm to merge. This can't be undone.")
+	}
+	return nil
+}
+
+func (s *Server) teamCommand(sess ssh.Session, args []string) error {
+	const usage = "usage: team [create <name> | join <name> | leave | list | leaderboard [-average]]"
+
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+	ctx := sess.Context()
+
+	if len(args) == 0 {
+		team, err := s.db.GetPlayerTeam(ctx, player.ID)
+		if errors.Is(err, storage.ErrNotInTeam) {
+			wish.Println(sess, "You are not in a team. Create one with: team create <name>")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		members, err := s.db.GetTeamMembers(ctx, team.ID)
+		if err != nil {
+			return err
+		}
+		wish.Printf(sess, "%s (%s)\n", team.Name, pluralMembers(team.Members))
+		for _, m := range members {
+			best := strconv.Itoa(m.BestScore)
+			if m.Hidden {
+				best = "hidden"
+			}
+			wish.Printf(sess, "  %-20s best %-8s joined %s\n", m.Username, best, m.JoinedAt.Format(time.DateOnly))
+		}
+		return nil
+	}
+
+	name := strings.Join(args[1:], " ")
+	switch args[0] {
+	case "create":
+		if name == "" {
+			return errors.New("usage: team create <name>")
+		}
+		team, err := s.db.CreateTeam(ctx, player.ID, name)
+		if err != nil {
+			return err
+		}
+		wish.Printf(sess, "Created team %s\n", team.Name)
+		return nil
+
+	case "join":
+		if name == "" {
+			return errors.New("usage: team join <name>")
+		}
+		team, err := s.db.JoinTeam(ctx, player.ID, name)
+		if err != nil {
+			return err
+		}
+		wish.Printf(sess, "Joined team %s (%s)\n", team.Name, pluralMembers(team.Members))
+		return nil
+
+	case "leave":
+		team, err := s.db.LeaveTeam(ctx, player.ID)
+		if err != nil {
+			return err
+		}
+		wish.Printf(sess, "Left team %s\n", team.Name)
+		return nil
+
+	case "list":
+		teams, err := s.db.GetTeams(ctx)
+		if err != nil {
+			return err
+		}
+		if len(teams) == 0 {
+			wish.Println(sess, "No teams yet")
+		}
+		for _, t := range teams {
+			wish.Printf(sess, "%-24s %s\n", t.Name, pluralMembers(t.Members))
+		}
+		return nil
+
+	case "leaderboard":
+		fs := flag.NewFlagSet("team leaderboard", flag.ContinueOnError)
+		fs.SetOutput(sess.Stderr())
+		average := fs.Bool("average", false, "rank by the average of members' best scores instead of the sum")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		scoring := storage.TeamScoringSum
+		if *average {
+			scoring = storage.TeamScoringAverage
+		}
+
+		season, err := s.db.CurrentSeason(ctx)
+		if errors.Is(err, storage.ErrSeasonNotFound) {
+			season = nil
+		} else if err != nil {
+			return err
+		}
+
+		standings, err := s.db.GetTeamLeaderboard(ctx, season, scoring, 10)
+		if err != nil {
+			return err
+		}
+		if season != nil {
+			wish.Printf(sess, "%s, %s of members' best scores\n", season.Name, scoring)
+		} else {
+			wish.Printf(sess, "All time, %s of members' best scores\n", scoring)
+		}
+		if len(standings) == 0 {
+			wish.Println(sess, "No team scores yet")
+		}
+		for _, st := range standings {
+			wish.Printf(sess, "%3d. %-24s %8d  (%d scoring)\n", st.Rank, st.Name, st.Score, st.Members)
+		}
+		return nil
+	}
+
+	return errors.New(usage)
+}
+
+func pluralMembers(n int) string {
+	if n == 1 {
+		return "1 member"
+	}
+	return fmt.Sprintf("%d members", n)
+}
+
+func (s *Server) privacyCommand(sess ssh.Session, args []string) error {
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if player.Hidden {
+			wish.Println(sess, "You are hidden from the public leaderboard")
+		} else {
+			wish.Println(sess, "You are shown on the public leaderboard")
+		}
+		return nil
+	}
+
+	var hidden bool
+	switch args[0] {
+	case "show":
+		hidden = false
+	case "hide":
+		hidden = true
+	default:
+		return errors.New("usage: privacy [show | hide]")
+	}
+
+	if err := s.db.SetHidden(sess.Context(), player.ID, hidden); err != nil {
+		return err
+	}
+	if hidden {
+		wish.Println(sess, "Your scores are now hidden from the public leaderboard")
+	} else {
+		wish.Println(sess, "Your scores are now shown on the public leaderboard")
+	}
+	return nil
+}
+
+func (s *Server) exportDataCommand(sess ssh.Session, args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: export-data")
+	}
+
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.db.ExportPlayerData(sess.Context(), player.ID)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(sess)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+func (s *Server) deleteAccountCommand(sess ssh.Session, args []string) error {
+	fs := flag.NewFlagSet("delete-account", flag.ContinueOnError)
+	fs.SetOutput(sess.Stderr())
+	confirm := fs.Bool("confirm", false, "delete the account instead of previewing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: delete-account [-confirm] <username>")
+	}
+
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(fs.Arg(0), player.Username) {
+		return fmt.Errorf("type your username (%s) to confirm", player.Username)
+	}
+
+	if !*confirm {
+		wish.Printf(sess, "This will permanently delete %s with all of its scores, keys and achievements.\n", player.Username)
+		wish.Println(sess, "Run again with -confirm to delete. This can't be undone.")
+		return nil
+	}
+
+	result, err := s.db.DeletePlayer(sess.Context(), player.ID, fmt.Sprintf("player:%d", player.ID))
+	if err != nil {
+		return err
+	}
+
+	log.FromContext(sess.Context()).Info("Player deleted their account", "player_id", player.ID)
+	wish.Printf(sess, "Deleted %s: %d scores, %d keys and %d achievements removed. Goodbye!\n",
+		result.Player.Username, result.Scores, result.Keys, result.Achievements)
+	return nil
+}
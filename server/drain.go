@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+
+	"github.com/rayhanadev/2048/ui"
+)
+
+// drainMiddleware turns away sessions that start while the server is
+// draining, and tells players whose session the drain ended why
+func (s *Server) drainMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			if s.draining.Load() {
+				s.reject(sess, "draining", remoteIP(sess.RemoteAddr()), s.getFingerprint(sess),
+					"The server is restarting. Please try again in a moment.")
+				return
+			}
+
+			next(sess)
+
+			// The program has restored the terminal, so the message stays visible
+			if live := sessionFromContext(sess.Context()); live != nil && live.drained.Load() {
+				wish.Println(sess, "The server restarted. Any game in progress was saved, so reconnect to pick up where you left off.")
+			}
+		}
+	}
+}
+
+// drain stops accepting connections and gives open sessions timeout to end
+// on their own, showing players a countdown. Sessions still open after that
+// are asked to save their game and quit, and those that don't within
+// timeoutSaveGrace are closed. A signal on force skips straight to closing.
+func (s *Server) drain(timeout time.Duration, force <-chan os.Signal) error {
+	s.draining.Store(true)
+	deadline := time.Now().Add(timeout)
+
+	sessions := s.sessions.list()
+	log.Info("Draining sessions", "sessions", len(sessions), "timeout", timeout)
+	for _, live := range sessions {
+		// Send blocks until the program reads the message, so a slow
+		// session doesn't hold up the rest
+		go live.send(ui.ShutdownWarningMsg{Deadline: deadline})
+	}
+
+	// Shutdown closes the listener straight away, then waits for every
+	// connection to close
+	ctx, cancel := context.WithDeadline(context.Background(), deadline.Add(timeoutSaveGrace))
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.server.Shutdown(ctx)
+	}()
+
+	select {
+	case <-shutdown:
+		log.Info("All sessions ended")
+		return nil
+	case <-force:
+		log.Warn("Forcing shutdown")
+		return s.server.Close()
+	case <-time.After(timeout):
+	}
+
+	remaining := s.sessions.list()
+	log.Info("Ending remaining sessions", "sessions", len(remaining))
+	for _, live := range remaining {
+		live.drained.Store(true)
+		go func() {
+			if !live.send(ui.SessionEndingMsg{}) {
+				// Exec commands have no game to save
+				live.session.Close()
+			}
+		}()
+	}
+
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			log.Info("All sessions ended")
+			return nil
+		}
+		log.Warn("Sessions did not end in time", "sessions", len(s.sessions.list()))
+	case <-force:
+		log.Warn("Forcing shutdown")
+	}
+	return s.server.Close()
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/rayhanadev/2048/metrics"
+	"github.com/rayhanadev/2048/storage"
+)
+
+// errGuestCommand is returned when a guest runs a command, since commands
+// act on the account of the session's key
+var errGuestCommand = errors.New("commands need an SSH key; connect with one to use them")
+
+// refusedKeyKey marks a connection that offered a banned or unlisted key,
+// so it can't fall back to playing as a guest
+type refusedKeyKey struct{}
+
+// isGuest reports whether a session authenticated without a key
+func isGuest(sess ssh.Session) bool {
+	return sess.PublicKey() == nil
+}
+
+// passwordHandler lets clients without a key in as guests. The password
+// isn't checked.
+func (s *Server) passwordHandler(ctx ssh.Context, _ string) bool {
+	return s.allowGuest(ctx)
+}
+
+// keyboardInteractiveHandler lets clients without a key in as guests without
+// asking any questions
+func (s *Server) keyboardInteractiveHandler(ctx ssh.Context, _ gossh.KeyboardInteractiveChallenge) bool {
+	return s.allowGuest(ctx)
+}
+
+func (s *Server) allowGuest(ctx ssh.Context) bool {
+	if refused, _ := ctx.Value(refusedKeyKey{}).(bool); refused {
+		metrics.AuthRejections.WithLabelValues("refused_key").Inc()
+		log.Warn("Rejected guest", "reason", "refused_key", "ip", remoteIP(ctx.RemoteAddr()))
+		return false
+	}
+	return true
+}
+
+func (s *Server) claimCommand(sess ssh.Session, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: claim <code>")
+	}
+
+	player, err := s.sessionPlayer(sess)
+	if err != nil {
+		return err
+	}
+
+	score, err := s.db.ClaimGuestRun(sess.Context(), args[0], player.ID)
+	if errors.Is(err, storage.ErrInvalidClaimCode) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim run: %w", err)
+	}
+
+	log.FromContext(sess.Context()).Info("Claimed guest run", "player", player.Username, "score", score.Score)
+	wish.Printf(sess, "Added your guest game (score %d, best tile %d) to %s\n", score.Score, score.MaxTile, player.Username)
+	return nil
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// healthCheckTimeout bounds how long one readiness check may take
+const healthCheckTimeout = 2 * time.Second
+
+// Readiness statuses. A degraded server still serves games, so it stays in
+// rotation, but players can't save anything.
+const (
+	statusOK          = "ok"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+)
+
+var (
+	// errNotListening is reported until the SSH listener is bound
+	errNotListening = errors.New("SSH listener is not bound")
+
+	// errDraining is reported once the server starts shutting down
+	errDraining = errors.New("server is draining")
+)
+
+// readiness is the body of a readiness response
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// handleHealthz reports that the process is alive and serving HTTP
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz reports whether the server can take new sessions. It
+// responds 503 when it can't, and 200 otherwise, including when only
+// writes are failing.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	result := s.checkReadiness(r.Context())
+
+	code := http.StatusOK
+	if result.Status == statusUnavailable {
+		code = http.StatusServiceUnavailable
+		log.Warn("Not ready", "checks", result.Checks)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(result)
+}
+
+// checkReadiness runs every readiness check
+func (s *Server) checkReadiness(ctx context.Context) readiness {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	result := readiness{Status: statusOK, Checks: make(map[string]string)}
+	check := func(name, failStatus string, err error) {
+		if err == nil {
+			result.Checks[name] = statusOK
+			return
+		}
+		result.Checks[name] = err.Error()
+		if result.Status != statusUnavailable {
+			result.Status = failStatus
+		}
+	}
+
+	switch {
+	case s.draining.Load():
+		check("ssh_listener", statusUnavailable, errDraining)
+	case !s.listening.Load():
+		check("ssh_listener", statusUnavailable, errNotListening)
+	default:
+		check("ssh_listener", statusUnavailable, nil)
+	}
+	check("database", statusUnavailable, s.db.CheckRead(ctx))
+	check("migrations", statusUnavailable, s.db.CheckSchema(ctx))
+	check("database_writes", statusDegraded, s.db.CheckWrite(ctx))
+
+	return result
+}
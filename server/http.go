@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/rayhanadev/2048/metrics"
+)
+
+// newHTTPServer returns the HTTP server for operational endpoints, or nil
+// when no HTTP address is configured
+func (s *Server) newHTTPServer() *http.Server {
+	if s.config.HTTPAddr == "" {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+
+	return &http.Server{
+		Addr:              s.config.HTTPAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// serveHTTP runs the HTTP server on listener until it is shut down
+func (s *Server) serveHTTP(listener net.Listener) {
+	log.Info("Starting HTTP server", "address", listener.Addr())
+	if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("HTTP server error", "error", err)
+	}
+}
+
+// shutdownHTTP stops the HTTP server, if there is one
+func (s *Server) shutdownHTTP() {
+	if s.http == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.http.Shutdown(ctx); err != nil {
+		log.Error("Failed to shut down HTTP server", "error", err)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+
+	"github.com/rayhanadev/2048/metrics"
+)
+
+// rateLimiter allows each address a burst of rate sessions, refilled
+// evenly over a minute
+type rateLimiter struct {
+	rate int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{rate: rate, buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token for addr, reporting false when none are left.
+// A zero rate allows everything.
+func (l *rateLimiter) allow(addr string, now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(l.rate)
+	perSecond := capacity / time.Minute.Seconds()
+
+	// Buckets that have refilled completely carry no state worth keeping
+	if now.Sub(l.lastSweep) >= time.Minute {
+		for key, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*perSecond >= capacity {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[addr]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[addr] = b
+	}
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// limitMiddleware rejects sessions from addresses connecting too often and
+// sessions beyond the per-key and server-wide caps
+func (s *Server) limitMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			ip := remoteIP(sess.RemoteAddr())
+			fingerprint := s.getFingerprint(sess)
+
+			if !s.limiter.allow(ip, time.Now()) {
+				s.reject(sess, "rate", ip, fingerprint, "You're connecting too often. Please wait a minute and try again.")
+				return
+			}
+
+			live, err := s.sessions.add(sess, fingerprint, ip)
+			switch {
+			case errors.Is(err, errServerFull):
+				s.reject(sess, "server_full", ip, fingerprint, "The server is full right now. Please try again in a few minutes.")
+				return
+			case errors.Is(err, errKeySessions):
+				s.reject(sess, "key_sessions", ip, fingerprint, fmt.Sprintf(
+					"This key already has the maximum of %d open sessions. Close one and try again.", s.config.MaxSessionsPerKey))
+				return
+			}
+			defer s.sessions.remove(live)
+			sess.Context().SetValue(liveSessionKey{}, live)
+			sess.Context().SetValue(log.ContextKey, log.With("session", live.ID))
+
+			next(sess)
+		}
+	}
+}
+
+// reject logs why a session was refused and tells the client
+func (s *Server) reject(sess ssh.Session, reason, ip, fingerprint, message string) {
+	metrics.SessionRejections.WithLabelValues(reason).Inc()
+	log.Warn("Rejected session", "reason", reason, "ip", ip, "fingerprint", fingerprint, "user", sess.User())
+	wish.Fatalln(sess, message)
+}
+
+// remoteIP returns the host part of a remote address
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
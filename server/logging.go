@@ -0,0 +1,38 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// logMiddleware logs when a session starts and ends. It runs after
+// limitMiddleware, which tags the session's logger with its ID.
+func (s *Server) logMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			logger := log.FromContext(sess.Context())
+			started := time.Now()
+			pty, _, _ := sess.Pty()
+
+			logger.Info("Session started",
+				"user", sess.User(),
+				"ip", remoteIP(sess.RemoteAddr()),
+				"fingerprint", s.getFingerprint(sess),
+				"guest", isGuest(sess),
+				"command", strings.Join(sess.Command(), " "),
+				"term", pty.Term,
+				"width", pty.Window.Width,
+				"height", pty.Window.Height,
+				"client", sess.Context().ClientVersion(),
+			)
+
+			next(sess)
+
+			logger.Info("Session ended", "duration", time.Since(started).Round(time.Millisecond))
+		}
+	}
+}
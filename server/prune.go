@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+// retentionPolicy builds the storage retention policy from configuration
+func (s *Server) retentionPolicy() storage.RetentionPolicy {
+	return storage.RetentionPolicy{
+		MaxAge:           time.Duration(s.config.ScoreRetentionDays) * 24 * time.Hour,
+		KeepTopPerPlayer: s.config.KeepTopScores,
+		KeepLeaderboard:  s.config.KeepLeaderboardScores,
+	}
+}
+
+// runPruner prunes old data every PruneInterval until ctx is cancelled
+func (s *Server) runPruner(ctx context.Context) {
+	if s.db == nil || s.config.PruneInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.db.Prune(ctx, s.retentionPolicy(), false)
+		if err != nil {
+			log.Error("Pruning failed", "error", err)
+		} else if report.Scores > 0 || report.ExpiredPairing > 0 || report.ExpiredGuest > 0 {
+			log.Info("Pruned old data",
+				"scores", report.Scores,
+				"players", report.Players,
+				"pairing_codes", report.ExpiredPairing,
+				"guest_runs", report.ExpiredGuest,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
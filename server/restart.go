@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/charmbracelet/log"
+)
+
+// listen returns the listener named name that the process this one replaced
+// handed over, or a new one on addr
+func listen(name, addr string) (net.Listener, error) {
+	listener, err := inheritedListener(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inherit %s listener: %w", name, err)
+	}
+	if listener != nil {
+		log.Info("Inherited listener", "listener", name, "address", listener.Addr())
+		return listener, nil
+	}
+
+	listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return listener, nil
+}
+
+// handOff stops accepting connections once a new process has taken over the
+// listeners, then waits for this process's sessions to end however long
+// they take. Only a shutdown signal meanwhile drains them within DrainTimeout.
+func (s *Server) handOff(signals <-chan os.Signal) error {
+	log.Info("Handed off listeners, waiting for sessions to end", "sessions", len(s.sessions.list()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.server.Shutdown(ctx)
+	}()
+
+	select {
+	case <-shutdown:
+		log.Info("All sessions ended")
+		return nil
+	case <-signals:
+		cancel()
+		<-shutdown
+		log.Info("Shutting down server...")
+		return s.drain(s.config.DrainTimeout, signals)
+	}
+}
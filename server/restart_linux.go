@@ -0,0 +1,185 @@
+//go:build linux
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// envInheritedFiles names the files a restarting server passes to the new
+// process, in order starting at file descriptor 3
+const envInheritedFiles = "SSH2048_INHERITED_FILES"
+
+// restartReadyTimeout is how long a new process gets to start serving
+// before the restart is abandoned
+const restartReadyTimeout = time.Minute
+
+// restartSignals are the signals that restart the server without dropping sessions
+func restartSignals() []os.Signal {
+	return []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+}
+
+// inheritedFile returns the file named name passed by the process this one
+// replaced, or nil if there is none
+func inheritedFile(name string) *os.File {
+	names := strings.Split(os.Getenv(envInheritedFiles), ",")
+	i := slices.Index(names, name)
+	if i < 0 {
+		return nil
+	}
+	return os.NewFile(uintptr(3+i), name)
+}
+
+func inheritedListener(name string) (net.Listener, error) {
+	file := inheritedFile(name)
+	if file == nil {
+		return nil, nil
+	}
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+// signalReady tells the process this one replaced that it is serving, so
+// the old process can stop accepting connections
+func signalReady() {
+	file := inheritedFile("ready")
+	if file == nil {
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write([]byte{1}); err != nil {
+		log.Warn("Failed to signal ready", "error", err)
+	}
+}
+
+// restart starts a new server process from the current executable, handing
+// it the listening sockets, and waits until it is serving. The caller keeps
+// serving its sessions either way, so a failed restart drops nobody.
+//
+// Under systemd the new process is a child of the old one, and would be
+// killed once the old one exits. restart makes it the service's main process
+// instead, which systemd only accepts with NotifyAccess=all in the unit.
+func (s *Server) restart() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find executable: %w", err)
+	}
+
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	defer ready.Close()
+
+	names := []string{"ready"}
+	files := []*os.File{readyWriter}
+	for _, l := range []struct {
+		name     string
+		listener net.Listener
+	}{{"ssh", s.listener}, {"http", s.httpListener}} {
+		if l.listener == nil {
+			continue
+		}
+		file, err := l.listener.(*net.TCPListener).File()
+		if err != nil {
+			closeFiles(files)
+			return fmt.Errorf("failed to get %s listener: %w", l.name, err)
+		}
+		names = append(names, l.name)
+		files = append(files, file)
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(slices.DeleteFunc(os.Environ(), func(v string) bool {
+		return strings.HasPrefix(v, envInheritedFiles+"=")
+	}), envInheritedFiles+"="+strings.Join(names, ","))
+
+	err = cmd.Start()
+	// The new process has its own copies now
+	closeFiles(files)
+	// Passing the sockets put them in blocking mode, and they share that
+	// with this process's listeners, which couldn't be closed otherwise
+	for _, listener := range []net.Listener{s.listener, s.httpListener} {
+		if listener != nil {
+			setNonblock(listener)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start new process: %w", err)
+	}
+	log.Info("Started new process", "pid", cmd.Process.Pid)
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	// A byte means the new process is serving; EOF means it exited first
+	readyErr := make(chan error, 1)
+	go func() {
+		_, err := ready.Read(make([]byte, 1))
+		readyErr <- err
+	}()
+
+	select {
+	case err := <-readyErr:
+		if err == nil {
+			notifyMainPID(cmd.Process.Pid)
+			return nil
+		}
+		return fmt.Errorf("new process exited before serving: %w", <-exited)
+	case <-time.After(restartReadyTimeout):
+		cmd.Process.Kill()
+		return errors.New("new process did not start serving in time")
+	}
+}
+
+// notifyMainPID tells systemd, if it started this process, that pid is the
+// service's main process now
+func notifyMainPID(pid int) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		log.Warn("Failed to notify systemd", "error", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintf(conn, "MAINPID=%d", pid); err != nil {
+		log.Warn("Failed to notify systemd", "error", err)
+	}
+}
+
+func setNonblock(listener net.Listener) {
+	raw, err := listener.(*net.TCPListener).SyscallConn()
+	if err != nil {
+		log.Warn("Failed to reset listener", "error", err)
+		return
+	}
+	raw.Control(func(fd uintptr) {
+		if err := syscall.SetNonblock(int(fd), true); err != nil {
+			log.Warn("Failed to reset listener", "error", err)
+		}
+	})
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// restartSignals are the signals that restart the server without dropping
+// sessions. Restarting is only supported on Linux.
+func restartSignals() []os.Signal {
+	return nil
+}
+
+func inheritedListener(string) (net.Listener, error) {
+	return nil, nil
+}
+
+func signalReady() {}
+
+func (s *Server) restart() error {
+	return errors.New("restarting is only supported on Linux")
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// seasonCheckInterval is how often ended seasons are looked for
+const seasonCheckInterval = time.Minute
+
+// runSeasonArchiver freezes the standings of ended seasons until ctx is cancelled
+func (s *Server) runSeasonArchiver(ctx context.Context) {
+	if s.db == nil {
+		return
+	}
+
+	ticker := time.NewTicker(seasonCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		archived, err := s.db.ArchiveDueSeasons(ctx, "system")
+		if err != nil {
+			log.Error("Archiving seasons failed", "error", err)
+		}
+		for _, season := range archived {
+			log.Info("Archived season", "season", season.Name, "ended", season.EndsAt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/ssh"
+
+	"github.com/rayhanadev/2048/metrics"
+)
+
+var (
+	// errServerFull is returned when MaxSessions sessions are already open
+	errServerFull = errors.New("server is full")
+
+	// errKeySessions is returned when a key already has MaxSessionsPerKey sessions open
+	errKeySessions = errors.New("too many sessions for key")
+)
+
+// liveSessionKey stores a session's *liveSession in its context
+type liveSessionKey struct{}
+
+// liveSession is an open SSH session
+type liveSession struct {
+	ID          uint64
+	Fingerprint string
+	RemoteIP    string
+	StartedAt   time.Time
+
+	session ssh.Session
+
+	// lastActive is when the player last sent input, in Unix nanoseconds
+	lastActive atomic.Int64
+
+	// drained is set when a server shutdown ends the session
+	drained atomic.Bool
+
+	mu      sync.Mutex
+	program *tea.Program
+}
+
+// sessionFromContext returns the live session registered for a connection
+func sessionFromContext(ctx ssh.Context) *liveSession {
+	live, _ := ctx.Value(liveSessionKey{}).(*liveSession)
+	return live
+}
+
+// touch records player input
+func (l *liveSession) touch() {
+	l.lastActive.Store(time.Now().UnixNano())
+}
+
+// idleSince returns when the player last sent input
+func (l *liveSession) idleSince() time.Time {
+	return time.Unix(0, l.lastActive.Load())
+}
+
+// filter is a tea.WithFilter function that records key presses and mouse
+// events as activity
+func (l *liveSession) filter(_ tea.Model, msg tea.Msg) tea.Msg {
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		l.touch()
+	}
+	return msg
+}
+
+func (l *liveSession) setProgram(p *tea.Program) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.program = p
+}
+
+// send delivers msg to the session's program, reporting false for sessions
+// without one such as exec commands
+func (l *liveSession) send(msg tea.Msg) bool {
+	l.mu.Lock()
+	p := l.program
+	l.mu.Unlock()
+
+	if p == nil {
+		return false
+	}
+	p.Send(msg)
+	return true
+}
+
+// sessionRegistry tracks open sessions and enforces the session caps.
+// A zero cap means unlimited.
+type sessionRegistry struct {
+	maxTotal  int
+	maxPerKey int
+
+	mu       sync.Mutex
+	nextID   uint64
+	sessions map[uint64]*liveSession
+	perKey   map[string]int
+}
+
+func newSessionRegistry(maxTotal, maxPerKey int) *sessionRegistry {
+	return &sessionRegistry{
+		maxTotal:  maxTotal,
+		maxPerKey: maxPerKey,
+		sessions:  make(map[uint64]*liveSession),
+		perKey:    make(map[string]int),
+	}
+}
+
+// add registers a session unless it would exceed a cap
+func (r *sessionRegistry) add(sess ssh.Session, fingerprint, remoteIP string) (*liveSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.maxTotal > 0 && len(r.sessions) >= r.maxTotal {
+		return nil, errServerFull
+	}
+	if r.maxPerKey > 0 && r.perKey[fingerprint] >= r.maxPerKey {
+		return nil, errKeySessions
+	}
+
+	r.nextID++
+	live := &liveSession{
+		ID:          r.nextID,
+		Fingerprint: fingerprint,
+		RemoteIP:    remoteIP,
+		StartedAt:   time.Now(),
+		session:     sess,
+	}
+	live.touch()
+	r.sessions[live.ID] = live
+	r.perKey[fingerprint]++
+	metrics.ActiveSessions.Inc()
+	return live, nil
+}
+
+// remove unregisters a session added with add
+func (r *sessionRegistry) remove(live *liveSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[live.ID]; !ok {
+		return
+	}
+	delete(r.sessions, live.ID)
+	metrics.ActiveSessions.Dec()
+	if r.perKey[live.Fingerprint]--; r.perKey[live.Fingerprint] <= 0 {
+		delete(r.perKey, live.Fingerprint)
+	}
+}
+
+// list returns the open sessions, oldest first
+func (r *sessionRegistry) list() []*liveSession {
+	r.mu.Lock()
+	sessions := make([]*liveSession, 0, len(r.sessions))
+	for _, live := range r.sessions {
+		sessions = append(sessions, live)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(sessions, func(a, b *liveSession) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return sessions
+}
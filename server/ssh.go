@@ -10,18 +10,19 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync/atomic"
 	"syscall"
-	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
 	"github.com/charmbracelet/wish/activeterm"
 	"github.com/charmbracelet/wish/bubbletea"
-	"github.com/charmbracelet/wish/logging"
+	"github.com/muesli/termenv"
 	gossh "golang.org/x/crypto/ssh"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -33,16 +34,39 @@ import (
 
 // Server represents the SSH server
 type Server struct {
-	config *config.Config
-	db     *storage.DB
-	server *ssh.Server
+	config    *config.Config
+	db        *storage.DB
+	server    *ssh.Server
+	limiter   *rateLimiter
+	sessions  *sessionRegistry
+	allowlist *allowlist
+	http      *http.Server
+
+	// listener and httpListener are handed to the new process on restart
+	listener     net.Listener
+	httpListener net.Listener
+
+	// listening is set while the SSH listener is bound, and draining once
+	// the server starts shutting down
+	listening atomic.Bool
+	draining  atomic.Bool
 }
 
 // NewServer creates a new SSH server
 func NewServer(cfg *config.Config, db *storage.DB) (*Server, error) {
 	s := &Server{
-		config: cfg,
-		db:     db,
+		config:   cfg,
+		db:       db,
+		limiter:  newRateLimiter(cfg.ConnRatePerIP),
+		sessions: newSessionRegistry(cfg.MaxSessions, cfg.MaxSessionsPerKey),
+	}
+
+	if cfg.AllowlistPath != "" {
+		allowlist, err := newAllowlist(cfg.AllowlistPath)
+		if err != nil {
+			return nil, err
+		}
+		s.allowlist = allowlist
 	}
 
 	// Ensure host key exists
@@ -51,60 +75,112 @@ func NewServer(cfg *config.Config, db *storage.DB) (*Server, error) {
 	}
 
 	// Create Wish server
-	server, err := wish.NewServer(
+	opts := []ssh.Option{
 		wish.WithAddress(net.JoinHostPort(cfg.SSHHost, fmt.Sprintf("%d", cfg.SSHPort))),
 		wish.WithHostKeyPath(cfg.HostKeyPath),
 		wish.WithPublicKeyAuth(s.publicKeyHandler),
+		func(srv *ssh.Server) error {
+			srv.ConnCallback = s.connCallback
+			return nil
+		},
 		wish.WithMiddleware(
-			bubbletea.Middleware(s.teaHandler),
+			bubbletea.MiddlewareWithProgramHandler(s.programHandler, termenv.Ascii),
 			activeterm.Middleware(),
-			logging.Middleware(),
+			s.commandMiddleware(),
+			s.timeoutMiddleware(),
+			s.banMiddleware(),
+			s.drainMiddleware(),
+			s.logMiddleware(),
+			s.limitMiddleware(),
 		),
-	)
+	}
+	if cfg.GuestAccess && s.allowlist == nil {
+		opts = append(opts,
+			wish.WithPasswordAuth(s.passwordHandler),
+			wish.WithKeyboardInteractiveAuth(s.keyboardInteractiveHandler),
+		)
+	}
+
+	server, err := wish.NewServer(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSH server: %w", err)
 	}
 
 	s.server = server
+	s.http = s.newHTTPServer()
 	return s, nil
 }
 
 // publicKeyHandler handles public key authentication
-// We accept all public keys (public access) but extract the fingerprint for player identification
+// Any key is accepted (public access) unless it is banned or an allowlist is
+// configured without it. The fingerprint identifies the player.
 func (s *Server) publicKeyHandler(ctx ssh.Context, key ssh.PublicKey) bool {
-	// Always accept - public access
-	// The key is stored in context for later use
-	return true
+	return s.allowKey(ctx, keyFingerprint(key))
 }
 
-// teaHandler creates a Bubbletea program for each SSH session
+// programHandler creates the Bubbletea program for a session and registers
+// it with the session so the server can send it messages
+func (s *Server) programHandler(sess ssh.Session) *tea.Program {
+	model, opts := s.teaHandler(sess)
+	live := sessionFromContext(sess.Context())
+	if live != nil {
+		opts = append(opts, tea.WithFilter(live.filter))
+	}
+
+	p := tea.NewProgram(model, append(opts, bubbletea.MakeOptions(sess)...)...)
+	if live != nil {
+		live.setProgram(p)
+	}
+	return p
+}
+
+// teaHandler creates the model and options for each SSH session
 func (s *Server) teaHandler(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
+	logger := log.FromContext(sess.Context())
+
 	// Get terminal size
 	pty, _, ok := sess.Pty()
 	if !ok {
-		log.Warn("No PTY requested, using default size")
+		logger.Warn("No PTY requested, using default size")
 	}
 
-	// Extract public key fingerprint
-	fingerprint := s.getFingerprint(sess)
+	var model ui.Model
+	if isGuest(sess) {
+		model = ui.NewModel(sess.Context(), s.db, "", nil, ui.StatePlaying).AsGuest()
+		logger.Info("Starting guest game")
+	} else {
+		// Extract public key fingerprint
+		fingerprint := s.getFingerprint(sess)
+
+		// Check if player exists
+		var player *storage.Player
+		if s.db != nil {
+			p, err := s.db.GetPlayerByFingerprint(sess.Context(), fingerprint)
+			if err == nil {
+				player = p
+				s.db.TouchKey(sess.Context(), fingerprint)
+			}
+		}
 
-	// Check if player exists
-	var player *storage.Player
-	if s.db != nil {
-		p, err := s.db.GetPlayerByFingerprint(fingerprint)
-		if err == nil {
-			player = p
+		// Create the model
+		var initialState ui.AppState
+		if player == nil {
+			initialState = ui.StateUsernameEntry
+		} else {
+			initialState = ui.StatePlaying
+		}
+		model = ui.NewModel(sess.Context(), s.db, fingerprint, player, initialState)
+		admin := s.config.IsAdmin(fingerprint)
+		if admin {
+			model = model.WithAdmin(adminTools{s})
 		}
-	}
 
-	// Create the model
-	var initialState ui.AppState
-	if player == nil {
-		initialState = ui.StateUsernameEntry
-	} else {
-		initialState = ui.StatePlaying
+		if player == nil {
+			logger.Info("Starting sign-up")
+		} else {
+			logger.Info("Starting game", "player", player.Username, "player_id", player.ID, "admin", admin)
+		}
 	}
-	model := ui.NewModel(s.db, fingerprint, player, initialState)
 
 	// Set initial terminal size
 	if ok {
@@ -114,6 +190,8 @@ func (s *Server) teaHandler(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
 	return model, []tea.ProgramOption{
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
+		// Signals are for the server; it ends sessions itself when draining
+		tea.WithoutSignalHandler(),
 	}
 }
 
@@ -121,11 +199,16 @@ func (s *Server) teaHandler(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
 func (s *Server) getFingerprint(sess ssh.Session) string {
 	key := sess.PublicKey()
 	if key == nil {
-		// Fallback for connections without public key (shouldn't happen with pubkey auth)
-		return fmt.Sprintf("anon-%s-%d", sess.RemoteAddr().String(), time.Now().UnixNano())
+		// Guests share a fingerprint per address, so the per-key session cap
+		// also limits guest sessions from one address
+		return "guest:" + remoteIP(sess.RemoteAddr())
 	}
 
-	// Generate SHA256 fingerprint
+	return keyFingerprint(key)
+}
+
+// keyFingerprint returns the SHA256 fingerprint of a public key
+func keyFingerprint(key ssh.PublicKey) string {
 	hash := sha256.Sum256(key.Marshal())
 	fingerprint := base64.RawStdEncoding.EncodeToString(hash[:])
 	return fmt.Sprintf("SHA256:%s", fingerprint)
@@ -179,24 +262,69 @@ func (s *Server) Start() error {
 	addr := net.JoinHostPort(s.config.SSHHost, fmt.Sprintf("%d", s.config.SSHPort))
 	log.Info("Starting SSH server", "address", addr)
 
+	var err error
+	if s.listener, err = listen("ssh", addr); err != nil {
+		return err
+	}
+	if s.http != nil {
+		if s.httpListener, err = listen("http", s.http.Addr); err != nil {
+			return err
+		}
+	}
+
 	// Handle graceful shutdown
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	// Restarting hands the listeners to a new process, and this one keeps
+	// serving its sessions until they end
+	restart := make(chan os.Signal, 1)
+	if signals := restartSignals(); len(signals) > 0 {
+		signal.Notify(restart, signals...)
+	}
+
+	// Background jobs stop when the server shuts down
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go s.runPruner(jobsCtx)
+	go s.runSeasonArchiver(jobsCtx)
+	go s.runTournamentScheduler(jobsCtx)
+
+	if s.http != nil {
+		go s.serveHTTP(s.httpListener)
+	}
+
+	s.listening.Store(true)
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
+		defer s.listening.Store(false)
+		if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
 			log.Error("Server error", "error", err)
 		}
 	}()
 
 	log.Info("SSH 2048 server is running", "address", addr)
 	log.Info("Connect with: ssh localhost -p " + fmt.Sprintf("%d", s.config.SSHPort))
-
-	<-done
-
-	log.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	return s.server.Shutdown(ctx)
+	signalReady()
+
+	for {
+		select {
+		case <-done:
+			log.Info("Shutting down server...")
+			stopJobs()
+			err = s.drain(s.config.DrainTimeout, done)
+			s.shutdownHTTP()
+			return err
+
+		case <-restart:
+			log.Info("Restarting server...")
+			if err := s.restart(); err != nil {
+				log.Error("Failed to restart, still serving", "error", err)
+				continue
+			}
+			// The new process runs the background jobs and serves HTTP now
+			stopJobs()
+			s.shutdownHTTP()
+			return s.handOff(done)
+		}
+	}
 }
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+
+	"github.com/rayhanadev/2048/ui"
+)
+
+const (
+	// timeoutCheckInterval is how often sessions are checked against their
+	// timeouts, and how often the countdown is refreshed
+	timeoutCheckInterval = time.Second
+
+	// timeoutSaveGrace is how long a program gets to save its game and quit
+	// before the session is closed anyway
+	timeoutSaveGrace = 5 * time.Second
+)
+
+// timeoutMiddleware closes sessions that have been idle for IdleTimeout or
+// open for MaxSessionDuration. Players get a countdown first, and their game
+// in progress is saved before they are disconnected.
+func (s *Server) timeoutMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			live := sessionFromContext(sess.Context())
+			if live == nil || (s.config.IdleTimeout <= 0 && s.config.MaxSessionDuration <= 0) {
+				next(sess)
+				return
+			}
+
+			ctx, cancel := context.WithCancel(sess.Context())
+			timedOut := make(chan string, 1)
+			go s.watchTimeouts(ctx, sess, live, timedOut)
+
+			next(sess)
+			cancel()
+
+			// The program has restored the terminal, so the message stays visible
+			select {
+			case reason := <-timedOut:
+				wish.Println(sess, timeoutMessage(reason))
+			default:
+			}
+		}
+	}
+}
+
+// watchTimeouts waits until the session times out or ctx is done. When the
+// session times out, the reason ("idle" or "max") is sent on timedOut before
+// the program is asked to quit.
+func (s *Server) watchTimeouts(ctx context.Context, sess ssh.Session, live *liveSession, timedOut chan<- string) {
+	ticker := time.NewTicker(timeoutCheckInterval)
+	defer ticker.Stop()
+
+	var warned bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deadline, idle := s.sessionDeadline(live)
+		now := time.Now()
+
+		switch {
+		case !now.Before(deadline):
+			reason := "max"
+			if idle {
+				reason = "idle"
+			}
+			log.FromContext(sess.Context()).Info("Closing session", "reason", reason, "fingerprint", live.Fingerprint,
+				"ip", live.RemoteIP, "duration", now.Sub(live.StartedAt).Round(time.Second))
+
+			timedOut <- reason
+			if !live.send(ui.SessionEndingMsg{}) {
+				// Exec commands have no game to save
+				sess.Close()
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(timeoutSaveGrace):
+				log.FromContext(sess.Context()).Warn("Session did not quit in time", "fingerprint", live.Fingerprint)
+				sess.Close()
+			}
+			return
+
+		case deadline.Sub(now) <= s.config.TimeoutWarning:
+			live.send(ui.TimeoutWarningMsg{Deadline: deadline, Idle: idle})
+			warned = true
+
+		case warned:
+			// The player became active again
+			live.send(ui.TimeoutWarningMsg{})
+			warned = false
+		}
+	}
+}
+
+// sessionDeadline returns when the session will be closed and whether that
+// is because it went idle
+func (s *Server) sessionDeadline(live *liveSession) (time.Time, bool) {
+	var deadline time.Time
+	if s.config.MaxSessionDuration > 0 {
+		deadline = live.StartedAt.Add(s.config.MaxSessionDuration)
+	}
+	if s.config.IdleTimeout > 0 {
+		idleDeadline := live.idleSince().Add(s.config.IdleTimeout)
+		if deadline.IsZero() || idleDeadline.Before(deadline) {
+			return idleDeadline, true
+		}
+	}
+	return deadline, false
+}
+
+func timeoutMessage(reason string) string {
+	if reason == "idle" {
+		return "Disconnected after being idle for too long. Any game in progress was saved, so reconnect to pick up where you left off."
+	}
+	return "Disconnected after reaching the session time limit. Any game in progress was saved, so reconnect to pick up where you left off."
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// tournamentPollInterval caps how long the scheduler sleeps, so tournaments
+// created while it waits are still picked up promptly
+const tournamentPollInterval = time.Minute
+
+// runTournamentScheduler opens tournaments when they start and finalizes
+// their results once they end, until ctx is cancelled
+func (s *Server) runTournamentScheduler(ctx context.Context) {
+	if s.db == nil {
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		opened, err := s.db.OpenDueTournaments(ctx)
+		if err != nil {
+			log.Error("Opening tournaments failed", "error", err)
+		}
+		for _, t := range opened {
+			log.Info("Opened tournament", "tournament", t.Name, "rounds", t.Rounds(), "ends", t.EndsAt)
+		}
+
+		finalized, err := s.db.FinalizeDueTournaments(ctx, "system")
+		if err != nil {
+			log.Error("Finalizing tournaments failed", "error", err)
+		}
+		for _, t := range finalized {
+			log.Info("Finalized tournament", "tournament", t.Name)
+		}
+
+		wait := tournamentPollInterval
+		next, ok, err := s.db.NextTournamentEvent(ctx)
+		if err != nil {
+			log.Error("Scheduling tournaments failed", "error", err)
+		}
+		if ok {
+			wait = min(max(time.Until(next), time.Second), tournamentPollInterval)
+		}
+		timer.Reset(wait)
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Achievement records when a player unlocked an achievement
+type Achievement struct {
+	PlayerID      int64
+	AchievementID string
+	UnlockedAt    time.Time
+}
+
+// UnlockAchievement records an achievement for a player.
+// Unlocking the same achievement twice is a no-op.
+func (db *DB) UnlockAchievement(ctx context.Context, playerID int64, achievementID string) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.StmtContext(ctx, db.stmts.unlockAchievement).ExecContext(ctx, playerID, achievementID)
+		return err
+	})
+}
+
+// GetAchievements returns every achievement a player has unlocked, oldest first
+func (db *DB) GetAchievements(ctx context.Context, playerID int64) ([]Achievement, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT player_id, achievement_id, unlocked_at
+		FROM achievements
+		WHERE player_id = ?
+		ORDER BY unlocked_at, achievement_id
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var achievements []Achievement
+	for rows.Next() {
+		var a Achievement
+		if err := rows.Scan(&a.PlayerID, &a.AchievementID, &a.UnlockedAt); err != nil {
+			return nil, err
+		}
+		achievements = append(achievements, a)
+	}
+
+	return achievements, rows.Err()
+}
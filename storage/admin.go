@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrScoreNotFound is returned when a score doesn't exist
+	ErrScoreNotFound = errors.New("score not found")
+
+	// ErrAlreadyBanned is returned when banning a player who is already banned
+	ErrAlreadyBanned = errors.New("player is already banned")
+
+	// ErrNotBanned is returned when unbanning a player who isn't banned
+	ErrNotBanned = errors.New("player is not banned")
+)
+
+// PlayerSummary is a player with the totals moderators look at first
+type PlayerSummary struct {
+	Player
+	Games     int
+	BestScore int
+	Keys      int
+}
+
+// SearchPlayers returns players whose username starts with prefix, ignoring
+// case, ordered by username. An empty prefix matches everyone.
+func (db *DB) SearchPlayers(ctx context.Context, prefix string, limit int) ([]PlayerSummary, error) {
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(usernameKey(prefix)) + "%"
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+playerColumns+`,
+			(SELECT COUNT(*) FROM scores WHERE player_id = p.id),
+			(SELECT COALESCE(MAX(score), 0) FROM scores WHERE player_id = p.id),
+			(SELECT COUNT(*) FROM player_keys WHERE player_id = p.id)
+		FROM players p
+		WHERE p.username_key LIKE ? ESCAPE '\'
+		ORDER BY p.username_key
+		LIMIT ?
+	`, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []PlayerSummary
+	for rows.Next() {
+		var s PlayerSummary
+		p := &s.Player
+		if err := rows.Scan(&p.ID, &p.PubkeyFingerprint, &p.Username, &p.CreatedAt, &p.Hidden, &p.BannedAt, &p.BanReason,
+			&s.Games, &s.BestScore, &s.Keys); err != nil {
+			return nil, err
+		}
+		players = append(players, s)
+	}
+
+	return players, rows.Err()
+}
+
+// AdminRename renames a player on a moderator's behalf. The new name is
+// validated as usual but the rename cooldown doesn't apply.
+func (db *DB) AdminRename(ctx context.Context, playerID int64, username, actor string) error {
+	if err := db.ValidateUsername(username); err != nil {
+		return err
+	}
+
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		old, err := renamePlayer(ctx, tx, playerID, username)
+		if err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, actor, "admin_rename", playerID, map[string]any{
+			"old_username": old,
+			"new_username": username,
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// BanPlayer stops a player from connecting with any of their keys
+func (db *DB) BanPlayer(ctx context.Context, playerID int64, reason, actor string) error {
+	return db.setBanned(ctx, playerID, true, strings.TrimSpace(reason), actor)
+}
+
+// UnbanPlayer lets a banned player connect again
+func (db *DB) UnbanPlayer(ctx context.Context, playerID int64, actor string) error {
+	return db.setBanned(ctx, playerID, false, "", actor)
+}
+
+func (db *DB) setBanned(ctx context.Context, playerID int64, banned bool, reason, actor string) error {
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		player := &Player{}
+		err := scanPlayer(tx.QueryRowContext(ctx, playerByIDQuery, playerID), player)
+		if err != nil {
+			return err
+		}
+
+		action := "unban_player"
+		details := map[string]any{"username": player.Username}
+		if banned {
+			if player.BannedAt.Valid {
+				return ErrAlreadyBanned
+			}
+			action = "ban_player"
+			details["reason"] = reason
+			_, err = tx.ExecContext(ctx, `
+				UPDATE players SET banned_at = ?, ban_reason = ? WHERE id = ?
+			`, time.Now().UTC().Format(timestampFormat), reason, playerID)
+		} else {
+			if !player.BannedAt.Valid {
+				return ErrNotBanned
+			}
+			_, err = tx.ExecContext(ctx, `UPDATE players SET banned_at = NULL, ban_reason = '' WHERE id = ?`, playerID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update ban: %w", err)
+		}
+
+		if err := writeAudit(ctx, tx, actor, action, playerID, details); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// DeleteScore removes a single score, such as one set by cheating.
+// Archived season standings are left as they were frozen.
+func (db *DB) DeleteScore(ctx context.Context, scoreID int64, actor string) error {
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var s Score
+		err := tx.QueryRowContext(ctx, `
+			SELECT player_id, score, max_tile, created_at FROM scores WHERE id = ?
+		`, scoreID).Scan(&s.PlayerID, &s.Score, &s.MaxTile, &s.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrScoreNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM scores WHERE id = ?`, scoreID); err != nil {
+			return fmt.Errorf("failed to delete score: %w", err)
+		}
+
+		if err := writeAudit(ctx, tx, actor, "delete_score", s.PlayerID, map[string]any{
+			"score_id":   scoreID,
+			"score":      s.Score,
+			"max_tile":   s.MaxTile,
+			"created_at": s.CreatedAt.UTC().Format(timestampFormat),
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// RecordAudit appends an entry to the audit log for actions that don't
+// otherwise touch the database, such as broadcasts. A zero playerID means
+// the action isn't about a particular player.
+func (db *DB) RecordAudit(ctx context.Context, actor, action string, playerID int64, details map[string]any) error {
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := writeAudit(ctx, tx, actor, action, playerID, details); err != nil {
+			return fmt.Errorf("failed to record audit entry: %w", err)
+		}
+		return nil
+	})
+}
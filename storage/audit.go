@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// writeAudit appends an entry to the audit log. A zero playerID is stored as NULL.
+func writeAudit(ctx context.Context, e execer, actor, action string, playerID int64, details any) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(ctx, `
+		INSERT INTO audit_log (actor, action, player_id, details)
+		VALUES (?, ?, NULLIF(?, 0), ?)
+	`, actor, action, playerID, string(data))
+	return err
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// BanKind is what a ban matches against
+type BanKind string
+
+const (
+	BanFingerprint BanKind = "fingerprint"
+	BanIP          BanKind = "ip"
+	BanCIDR        BanKind = "cidr"
+)
+
+// ErrBanNotFound is returned when no active ban matches
+var ErrBanNotFound = errors.New("ban not found")
+
+// Ban refuses connections from a key, an address or a network.
+// Bans without an expiry are permanent.
+type Ban struct {
+	ID        int64
+	Kind      BanKind
+	Value     string
+	Reason    string
+	ExpiresAt sql.NullTime
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// Active reports whether the ban still applies at now
+func (b *Ban) Active(now time.Time) bool {
+	return !b.ExpiresAt.Valid || b.ExpiresAt.Time.After(now)
+}
+
+const banColumns = `id, kind, value, reason, expires_at, created_by, created_at`
+
+func scanBan(row interface{ Scan(...any) error }, b *Ban) error {
+	err := row.Scan(&b.ID, &b.Kind, &b.Value, &b.Reason, &b.ExpiresAt, &b.CreatedBy, &b.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBanNotFound
+	}
+	return err
+}
+
+// normalizeBan checks a ban's value and puts it in the form lookups compare against
+func normalizeBan(kind BanKind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case BanFingerprint:
+		if !strings.HasPrefix(value, "SHA256:") || len(value) == len("SHA256:") {
+			return "", fmt.Errorf("invalid key fingerprint %q: expected SHA256:...", value)
+		}
+		return value, nil
+
+	case BanIP:
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid IP address %q", value)
+		}
+		return addr.Unmap().String(), nil
+
+	case BanCIDR:
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid CIDR %q", value)
+		}
+		return prefix.Masked().String(), nil
+	}
+	return "", fmt.Errorf("unknown ban kind %q", kind)
+}
+
+// AddBan bans a key fingerprint, IP address or CIDR range. A zero expiresAt
+// makes the ban permanent. Banning something already banned replaces the
+// earlier reason and expiry.
+func (db *DB) AddBan(ctx context.Context, kind BanKind, value, reason string, expiresAt time.Time, actor string) (*Ban, error) {
+	value, err := normalizeBan(kind, value)
+	if err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+
+	var expires any
+	if !expiresAt.IsZero() {
+		expires = expiresAt.UTC().Format(timestampFormat)
+	}
+
+	ban := &Ban{}
+	err = db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := scanBan(tx.QueryRowContext(ctx, `
+			INSERT INTO bans (kind, value, reason, expires_at, created_by, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (kind, value) DO UPDATE SET
+				reason = excluded.reason,
+				expires_at = excluded.expires_at,
+				created_by = excluded.created_by,
+				created_at = excluded.created_at
+			RETURNING `+banColumns,
+			kind, value, reason, expires, actor, time.Now().UTC().Format(timestampFormat)), ban)
+		if err != nil {
+			return fmt.Errorf("failed to add ban: %w", err)
+		}
+
+		return writeAudit(ctx, tx, actor, "add_ban", 0, map[string]any{
+			"ban_id":     ban.ID,
+			"kind":       kind,
+			"value":      value,
+			"reason":     reason,
+			"expires_at": expires,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// RemoveBan lifts a ban
+func (db *DB) RemoveBan(ctx context.Context, banID int64, actor string) (*Ban, error) {
+	ban := &Ban{}
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := scanBan(tx.QueryRowContext(ctx, `DELETE FROM bans WHERE id = ? RETURNING `+banColumns, banID), ban)
+		if err != nil {
+			return err
+		}
+
+		return writeAudit(ctx, tx, actor, "remove_ban", 0, map[string]any{
+			"ban_id": ban.ID,
+			"kind":   ban.Kind,
+			"value":  ban.Value,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// GetBans returns the active bans, newest first, along with expired ones
+// when includeExpired is set
+func (db *DB) GetBans(ctx context.Context, includeExpired bool) ([]Ban, error) {
+	query := `SELECT ` + banColumns + ` FROM bans`
+	args := []any{}
+	if !includeExpired {
+		query += ` WHERE expires_at IS NULL OR expires_at > ?`
+		args = append(args, time.Now().UTC().Format(timestampFormat))
+	}
+	return db.queryBans(ctx, query+` ORDER BY created_at DESC, id DESC`, args...)
+}
+
+// FindKeyBan returns the active ban on a key fingerprint, or ErrBanNotFound
+func (db *DB) FindKeyBan(ctx context.Context, fingerprint string) (*Ban, error) {
+	ban := &Ban{}
+	err := scanBan(db.conn.QueryRowContext(ctx, `
+		SELECT `+banColumns+` FROM bans
+		WHERE kind = ? AND value = ? AND (expires_at IS NULL OR expires_at > ?)
+	`, BanFingerprint, fingerprint, time.Now().UTC().Format(timestampFormat)), ban)
+	if err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// FindAddressBan returns an active ban on an IP address or a range
+// containing it, or ErrBanNotFound
+func (db *DB) FindAddressBan(ctx context.Context, addr netip.Addr) (*Ban, error) {
+	addr = addr.Unmap()
+	bans, err := db.queryBans(ctx, `
+		SELECT `+banColumns+` FROM bans
+		WHERE ((kind = ? AND value = ?) OR kind = ?) AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY kind = ? DESC
+	`, BanIP, addr.String(), BanCIDR, time.Now().UTC().Format(timestampFormat), BanIP)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, ban := range bans {
+		if ban.Kind == BanIP {
+			return &bans[i], nil
+		}
+		// Ranges were validated when added, so a parse failure means the row
+		// was edited by hand and can't match anything
+		if prefix, err := netip.ParsePrefix(ban.Value); err == nil && prefix.Contains(addr) {
+			return &bans[i], nil
+		}
+	}
+	return nil, ErrBanNotFound
+}
+
+// queryBans returns the bans matched by a query selecting banColumns
+func (db *DB) queryBans(ctx context.Context, query string, args ...any) ([]Ban, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []Ban
+	for rows.Next() {
+		var b Ban
+		if err := scanBan(rows, &b); err != nil {
+			return nil, err
+		}
+		bans = append(bans, b)
+	}
+	return bans, rows.Err()
+}
@@ -1,71 +1,202 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 
-	_ "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+// ErrClosed is returned by writes made after the database was closed
+var ErrClosed = errors.New("database is closed")
+
+// Options tunes the connection pool and write queue
+type Options struct {
+	// MaxOpenConns caps the pool. SQLite allows one writer at a time, so
+	// extra connections only help concurrent readers.
+	MaxOpenConns int
+	// BusyTimeout is how long a connection waits for a lock before failing with SQLITE_BUSY
+	BusyTimeout time.Duration
+	// WriteQueueSize is how many writes may wait for the single writer.
+	// Zero disables the queue and writes go straight to the pool.
+	WriteQueueSize int
+	// SkipMigrations opens the database with whatever schema it has, for
+	// commands that must not upgrade it under a running server. Callers
+	// check CheckSchema before relying on the schema.
+	SkipMigrations bool
+}
+
+// DefaultOptions returns pool settings suited to a few hundred sessions
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:   8,
+		BusyTimeout:    5 * time.Second,
+		WriteQueueSize: 256,
+	}
+}
+
 // DB wraps the SQLite database connection
 type DB struct {
-	conn *sql.DB
+	conn      *sql.DB
+	usernames UsernamePolicy
+	stmts     statements
+
+	writer     *sql.Conn
+	writes     chan writeRequest
+	closing    chan struct{}
+	writerDone chan struct{}
+	closeOnce  sync.Once
+
+	writeHealth writeHealth
+}
+
+// statements are prepared once for the queries run on every connection or game
+type statements struct {
+	playerByFingerprint *sql.Stmt
+	playerBestScore     *sql.Stmt
+	saveScore           *sql.Stmt
+	unlockAchievement   *sql.Stmt
+	touchKey            *sql.Stmt
 }
 
 // NewDB creates a new database connection and initializes the schema
-func NewDB(dbPath string) (*DB, error) {
-	conn, err := sql.Open("sqlite", dbPath)
-	if err != nil {
+func NewDB(ctx context.Context, dbPath string, opts Options) (*DB, error) {
+	// Pragmas in the DSN apply to every pooled connection, not just the first.
+	// Immediate transactions take the write lock up front, so two writers
+	// wait on busy_timeout instead of failing when upgrading a read lock.
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", "busy_timeout("+strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10)+")")
+	params.Set("_txlock", "immediate")
+
+	conn := sql.OpenDB(newInstrumentedConnector(dbPath + "?" + params.Encode()))
+
+	if opts.MaxOpenConns > 0 {
+		// One more than asked for, reserved for the writer
+		conns := opts.MaxOpenConns
+		if opts.WriteQueueSize > 0 {
+			conns++
+		}
+		conn.SetMaxOpenConns(conns)
+		conn.SetMaxIdleConns(conns)
+	}
+	conn.SetConnMaxIdleTime(5 * time.Minute)
+
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Enable foreign keys
-	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	db := &DB{
+		conn:       conn,
+		closing:    make(chan struct{}),
+		writerDone: make(chan struct{}),
 	}
 
-	// Enable WAL mode for better concurrency
-	if _, err := conn.Exec("PRAGMA journal_mode = WAL"); err != nil {
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	if !opts.SkipMigrations {
+		if err := db.migrate(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
 	}
 
-	db := &DB{conn: conn}
+	// The statements need the current schema, which an unmigrated database may not have
+	if !opts.SkipMigrations || db.CheckSchema(ctx) == nil {
+		if err := db.prepare(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to prepare statements: %w", err)
+		}
+	}
 
-	if err := db.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	if opts.WriteQueueSize > 0 {
+		// The writer keeps its own connection so it never waits behind readers for one
+		var err error
+		if db.writer, err = conn.Conn(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to open writer connection: %w", err)
+		}
+		db.writes = make(chan writeRequest, opts.WriteQueueSize)
+		go db.runWriter()
+	} else {
+		close(db.writerDone)
 	}
 
 	return db, nil
 }
 
-// Close closes the database connection
+func (db *DB) prepare(ctx context.Context) error {
+	var err error
+	prepare := func(query string) *sql.Stmt {
+		if err != nil {
+			return nil
+		}
+		var stmt *sql.Stmt
+		stmt, err = db.conn.PrepareContext(ctx, query)
+		return stmt
+	}
+
+	db.stmts = statements{
+		playerByFingerprint: prepare(`
+			SELECT ` + playerColumns + `
+			FROM player_keys k
+			JOIN players p ON p.id = k.player_id
+			WHERE k.fingerprint = ?
+		`),
+		playerBestScore: prepare(`SELECT MAX(score) FROM scores WHERE player_id = ?`),
+		saveScore: prepare(`
+			INSERT INTO scores (player_id, score, max_tile, moves, duration_seconds)
+			VALUES (?, ?, ?, ?, ?)
+		`),
+		unlockAchievement: prepare(`
+			INSERT OR IGNORE INTO achievements (player_id, achievement_id)
+			VALUES (?, ?)
+		`),
+		touchKey: prepare(`
+			UPDATE player_keys SET last_used_at = CURRENT_TIMESTAMP WHERE fingerprint = ?
+		`),
+	}
+	return err
+}
+
+// Close waits for queued writes to finish and closes the database connection
 func (db *DB) Close() error {
+	db.closeOnce.Do(func() {
+		close(db.closing)
+	})
+	<-db.writerDone
+	if db.writer != nil {
+		db.writer.Close()
+	}
+
+	for _, stmt := range []*sql.Stmt{
+		db.stmts.playerByFingerprint,
+		db.stmts.playerBestScore,
+		db.stmts.saveScore,
+		db.stmts.unlockAchievement,
+		db.stmts.touchKey,
+	} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
 	return db.conn.Close()
 }
 
-// migrate creates the database schema
-func (db *DB) migrate() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS players (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		pubkey_fingerprint TEXT UNIQUE NOT NULL,
-		username TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS scores (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		player_id INTEGER NOT NULL,
-		score INTEGER NOT NULL,
-		max_tile INTEGER NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (player_id) REFERENCES players(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_scores_score ON scores(score DESC);
-	CREATE INDEX IF NOT EXISTS idx_players_fingerprint ON players(pubkey_fingerprint);
-	`
-
-	_, err := db.conn.Exec(schema)
-	return err
+// IsBusy reports whether err means SQLite gave up waiting for a lock
+func IsBusy(err error) bool {
+	var coded interface{ Code() int }
+	if !errors.As(err, &coded) {
+		return false
+	}
+	// Extended codes such as SQLITE_BUSY_SNAPSHOT keep the primary code in the low byte
+	code := coded.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
 }
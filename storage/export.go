@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ExportTables lists the tables that can be exported, parents before children
+// so that an import in this order satisfies foreign keys
+var ExportTables = []string{
+	"players",
+	"player_keys",
+	"scores",
+	"pruned_scores",
+	"pruned_days",
+	"achievements",
+	"username_history",
+	"follows",
+	"teams",
+	"team_members",
+	"seasons",
+	"season_standings",
+	"tournaments",
+	"tournament_rounds",
+	"tournament_attempts",
+	"tournament_results",
+	"saved_games",
+	"bans",
+	"audit_log",
+}
+
+// ConflictPolicy controls what an import does with rows whose key already exists
+type ConflictPolicy string
+
+const (
+	ConflictSkip    ConflictPolicy = "skip"
+	ConflictReplace ConflictPolicy = "replace"
+	ConflictFail    ConflictPolicy = "fail"
+)
+
+// timestampFormat matches SQLite's CURRENT_TIMESTAMP. Exports use it so
+// imported rows behave exactly like rows written by the server.
+const timestampFormat = "2006-01-02 15:04:05"
+
+// ImportResult counts what happened to the rows of one table
+type ImportResult struct {
+	Table    string
+	Inserted int64
+	Skipped  int64
+}
+
+// Backup writes a consistent snapshot of the live database to path.
+// It is safe to run while the server is writing.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	_, err := db.conn.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
+
+// ExportRows streams every row of table to fn. Values are nil, int64,
+// float64 or string; timestamps are formatted like CURRENT_TIMESTAMP.
+func (db *DB) ExportRows(ctx context.Context, table string, fn func(columns []string, values []any) error) error {
+	if !isExportTable(table) {
+		return fmt.Errorf("unknown table %q", table)
+	}
+
+	// The table name is checked against ExportTables above
+	rows, err := db.conn.QueryContext(ctx, `SELECT * FROM `+table+` ORDER BY rowid`)
+	if err != nil {
+		return err
+	}
+	return scanRows(rows, fn)
+}
+
+// ImportRows inserts rows into table inside a single transaction.
+// next returns the following row's values keyed by column, or nil when done.
+// Rows of tables that other tables refer to, such as players, are never
+// skipped or replaced: the rows referring to them would end up attached to
+// an unrelated row with the same id, or be deleted along with the old one.
+func (db *DB) ImportRows(ctx context.Context, table string, policy ConflictPolicy, next func() (map[string]any, error)) (*ImportResult, error) {
+	if !isExportTable(table) {
+		return nil, fmt.Errorf("unknown table %q", table)
+	}
+
+	var verb string
+	switch policy {
+	case ConflictSkip:
+		verb = "INSERT OR IGNORE"
+	case ConflictReplace:
+		verb = "INSERT OR REPLACE"
+	case ConflictFail:
+		verb = "INSERT"
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q", policy)
+	}
+
+	known, err := db.Columns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced, err := db.hasDependants(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if referenced && policy == ConflictReplace {
+		return nil, fmt.Errorf("%s can't be imported with conflict policy %q because other tables refer to its rows", table, policy)
+	}
+
+	result := &ImportResult{Table: table}
+	err = db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for {
+			row, err := next()
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				break
+			}
+
+			columns := make([]string, 0, len(row))
+			args := make([]any, 0, len(row))
+			for col, v := range row {
+				if _, ok := known[col]; !ok {
+					return fmt.Errorf("%s has no column %q", table, col)
+				}
+				columns = append(columns, col)
+				args = append(args, v)
+			}
+
+			query := fmt.Sprintf("%s INTO %s (%s) VALUES (%s)",
+				verb, table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+			n, err := execCount(ctx, tx, query, args...)
+			if err != nil {
+				return fmt.Errorf("%s: %w", table, err)
+			}
+			if n == 0 && referenced {
+				return fmt.Errorf("%s: a row conflicts with an existing one that other tables refer to", table)
+			}
+			if n == 0 {
+				result.Skipped++
+			} else {
+				result.Inserted++
+			}
+		}
+
+		if err := checkForeignKeys(ctx, tx, table); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Column describes a table column as declared in its schema
+type Column struct {
+	Type    string
+	NotNull bool
+}
+
+// Columns returns the columns of a table keyed by name
+func (db *DB) Columns(ctx context.Context, table string) (map[string]Column, error) {
+	rows, err := db.conn.QueryContext(ctx, `SELECT name, type, "notnull" FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]Column)
+	for rows.Next() {
+		var name string
+		var col Column
+		if err := rows.Scan(&name, &col.Type, &col.NotNull); err != nil {
+			return nil, err
+		}
+		columns[name] = col
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("table " + table + " does not exist")
+	}
+
+	return columns, rows.Err()
+}
+
+// hasDependants reports whether any table has a foreign key to table
+func (db *DB) hasDependants(ctx context.Context, table string) (bool, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM sqlite_master m, pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table' AND f."table" = ?
+	`, table).Scan(&n)
+	return n > 0, err
+}
+
+func isExportTable(table string) bool {
+	for _, t := range ExportTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
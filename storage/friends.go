@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxFollows caps how many players one player can follow
+const MaxFollows = 100
+
+var (
+	// ErrFollowSelf is returned when a player tries to follow themselves
+	ErrFollowSelf = errors.New("you can't follow yourself")
+
+	// ErrAlreadyFollowing is returned when following someone twice
+	ErrAlreadyFollowing = errors.New("you already follow that player")
+
+	// ErrNotFollowing is returned when unfollowing someone who isn't followed
+	ErrNotFollowing = errors.New("you don't follow that player")
+
+	// ErrTooManyFollows is returned once a player follows MaxFollows players
+	ErrTooManyFollows = fmt.Errorf("you can follow at most %d players", MaxFollows)
+)
+
+// Friend is a player that someone follows.
+// BestScore is zero when the friend hides from leaderboards.
+type Friend struct {
+	PlayerID   int64
+	Username   string
+	Hidden     bool
+	BestScore  int
+	FollowedAt time.Time
+}
+
+// HeadToHead compares a player with someone they follow. Wins and Losses
+// count the UTC days both played on which the player's best score beat,
+// or lost to, the friend's best score that day.
+type HeadToHead struct {
+	Username    string
+	GamesPlayed int
+	BestScore   int
+	HighestTile int
+	Wins        int
+	Losses      int
+}
+
+// Follow makes playerID follow the player with the given username
+func (db *DB) Follow(ctx context.Context, playerID int64, username string) (*Player, error) {
+	followee, err := db.GetPlayerByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if followee.ID == playerID {
+		return nil, ErrFollowSelf
+	}
+
+	err = db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM follows WHERE follower_id = ?`, playerID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= MaxFollows {
+			return ErrTooManyFollows
+		}
+
+		n, err := execCount(ctx, tx, `
+			INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)
+			ON CONFLICT (follower_id, followee_id) DO NOTHING
+		`, playerID, followee.ID)
+		if err != nil {
+			return fmt.Errorf("failed to follow player: %w", err)
+		}
+		if n == 0 {
+			return ErrAlreadyFollowing
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return followee, nil
+}
+
+// Unfollow stops playerID from following followeeID
+func (db *DB) Unfollow(ctx context.Context, playerID, followeeID int64) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		n, err := execCount(ctx, tx, `DELETE FROM follows WHERE follower_id = ? AND followee_id = ?`, playerID, followeeID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrNotFollowing
+		}
+		return nil
+	})
+}
+
+// GetFollowing returns the players someone follows, ordered by username
+func (db *DB) GetFollowing(ctx context.Context, playerID int64) ([]Friend, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT
+			p.id,
+			p.username,
+			p.hidden,
+			CASE WHEN p.hidden THEN 0 ELSE COALESCE(MAX(s.score), 0) END,
+			f.created_at
+		FROM follows f
+		JOIN players p ON p.id = f.followee_id
+		LEFT JOIN scores s ON s.player_id = p.id
+		WHERE f.follower_id = ?
+		GROUP BY p.id
+		ORDER BY p.username_key
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friends []Friend
+	for rows.Next() {
+		var f Friend
+		if err := rows.Scan(&f.PlayerID, &f.Username, &f.Hidden, &f.BestScore, &f.FollowedAt); err != nil {
+			return nil, err
+		}
+		friends = append(friends, f)
+	}
+
+	return friends, rows.Err()
+}
+
+// friendCircle selects the ids of a player and the visible players they
+// follow. It takes the player's id twice.
+const friendCircle = `
+	SELECT ? AS player_id
+	UNION
+	SELECT f.followee_id
+	FROM follows f
+	JOIN players p ON p.id = f.followee_id
+	WHERE f.follower_id = ? AND p.hidden = 0
+`
+
+// GetFriendsLeaderboard ranks the best score of a player and each visible
+// player they follow, in a season or of all time when season is nil.
+// Archived seasons rank the frozen standings.
+func (db *DB) GetFriendsLeaderboard(ctx context.Context, playerID int64, season *Season, limit int) ([]LeaderboardEntry, error) {
+	source, seasonArgs := seasonScores(season)
+	args := []any{playerID, playerID}
+	args = append(args, seasonArgs...)
+	args = append(args, limit)
+
+	rows, err := db.conn.QueryContext(ctx, `
+		WITH circle AS (`+friendCircle+`),
+		best AS (
+			SELECT s.player_id, s.score, s.max_tile, s.created_at,
+				ROW_NUMBER() OVER (PARTITION BY s.player_id ORDER BY s.score DESC, s.created_at) AS n
+			FROM (`+source+`) s
+			JOIN circle c ON c.player_id = s.player_id
+		)
+		SELECT p.username, b.score, b.max_tile, b.created_at
+		FROM best b
+		JOIN players p ON p.id = b.player_id
+		WHERE b.n = 1
+		ORDER BY b.score DESC, b.created_at
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	rank := 1
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.Username, &e.Score, &e.MaxTile, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Rank = rank
+		rank++
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetHeadToHead compares a player with every visible player they follow,
+// ordered by username
+func (db *DB) GetHeadToHead(ctx context.Context, playerID int64) ([]HeadToHead, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		WITH circle AS (`+friendCircle+`),
+		daily AS (
+			SELECT s.player_id, date(s.created_at) AS day, MAX(s.score) AS best
+			FROM scores s
+			JOIN circle c ON c.player_id = s.player_id
+			GROUP BY s.player_id, day
+		),
+		totals AS (
+			SELECT s.player_id, SUM(s.games) AS games, MAX(s.score) AS best, MAX(s.max_tile) AS tile
+			FROM (`+playerGames+`) s
+			JOIN circle c ON c.player_id = s.player_id
+			GROUP BY s.player_id
+		),
+		duels AS (
+			SELECT them.player_id,
+				SUM(me.best > them.best) AS wins,
+				SUM(me.best < them.best) AS losses
+			FROM daily me
+			JOIN daily them ON them.day = me.day AND them.player_id != me.player_id
+			WHERE me.player_id = ?
+			GROUP BY them.player_id
+		)
+		SELECT
+			p.username,
+			COALESCE(t.games, 0),
+			COALESCE(t.best, 0),
+			COALESCE(t.tile, 0),
+			COALESCE(d.wins, 0),
+			COALESCE(d.losses, 0)
+		FROM circle c
+		JOIN players p ON p.id = c.player_id
+		LEFT JOIN totals t ON t.player_id = p.id
+		LEFT JOIN duels d ON d.player_id = p.id
+		WHERE p.id != ?
+		ORDER BY p.username_key
+	`, playerID, playerID, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []HeadToHead
+	for rows.Next() {
+		var h HeadToHead
+		if err := rows.Scan(&h.Username, &h.GamesPlayed, &h.BestScore, &h.HighestTile, &h.Wins, &h.Losses); err != nil {
+			return nil, err
+		}
+		results = append(results, h)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GuestRunTTL is how long a guest has to claim a finished game
+const GuestRunTTL = 24 * time.Hour
+
+// ErrInvalidClaimCode is returned for unknown, claimed or expired claim codes
+var ErrInvalidClaimCode = errors.New("claim code is invalid or has expired")
+
+// SaveGuestRun keeps a game finished by a guest so a player can claim it
+// later, returning the claim code and when it expires
+func (db *DB) SaveGuestRun(ctx context.Context, score, maxTile, moves int, duration time.Duration) (string, time.Time, error) {
+	code, err := randomCode(pairingCodeLength)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().UTC().Add(GuestRunTTL)
+
+	err = db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO guest_runs (code, score, max_tile, moves, duration_seconds, expires_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, code, score, maxTile, moves, int64(duration.Seconds()),
+			expiresAt.Format(timestampFormat), time.Now().UTC().Format(timestampFormat)); err != nil {
+			return fmt.Errorf("failed to save guest run: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return code, expiresAt, nil
+}
+
+// ClaimGuestRun adds a guest's game to a player's scores, dated when it was
+// played. The code is consumed on success.
+func (db *DB) ClaimGuestRun(ctx context.Context, code string, playerID int64) (*Score, error) {
+	s := &Score{PlayerID: playerID}
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var seconds int64
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM guest_runs WHERE code = ? AND expires_at > ?
+			RETURNING score, max_tile, moves, duration_seconds, created_at
+		`, normalizeCode(code), time.Now().UTC().Format(timestampFormat)).Scan(&s.Score, &s.MaxTile, &s.Moves, &seconds, &s.CreatedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidClaimCode
+		}
+		if err != nil {
+			return err
+		}
+		s.Duration = time.Duration(seconds) * time.Second
+
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO scores (player_id, score, max_tile, moves, duration_seconds, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, playerID, s.Score, s.MaxTile, s.Moves, seconds, s.CreatedAt.UTC().Format(timestampFormat)).Scan(&s.ID); err != nil {
+			return fmt.Errorf("failed to save claimed score: %w", err)
+		}
+
+		return writeAudit(ctx, tx, fmt.Sprintf("player:%d", playerID), "claim_guest_run", playerID, map[string]any{
+			"score_id": s.ID,
+			"score":    s.Score,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrSchemaOutdated is returned by CheckSchema when the database's schema
+// doesn't match the migrations this build knows about
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// writeHealth remembers whether the last write transaction failed
+type writeHealth struct {
+	mu  sync.Mutex
+	err error
+}
+
+// record notes the outcome of a write transaction. Callers giving up on a
+// write says nothing about the database, so cancellations are ignored.
+func (h *writeHealth) record(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrClosed) {
+		return
+	}
+	h.mu.Lock()
+	h.err = err
+	h.mu.Unlock()
+}
+
+// isWriteFault reports whether a write's error means the database can't
+// write, such as a full disk or a lock it couldn't get. Constraint violations
+// are the write's own problem, like a taken username, so they don't count.
+func isWriteFault(err error) bool {
+	var coded interface{ Code() int }
+	if !errors.As(err, &coded) {
+		return false
+	}
+	switch coded.Code() & 0xff {
+	case sqlite3.SQLITE_IOERR, sqlite3.SQLITE_FULL, sqlite3.SQLITE_READONLY,
+		sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_NOTADB, sqlite3.SQLITE_CANTOPEN,
+		sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
+
+func (h *writeHealth) last() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// CheckRead runs a trivial query to confirm the database answers
+func (db *DB) CheckRead(ctx context.Context) error {
+	var one int
+	if err := db.conn.QueryRowContext(ctx, `SELECT 1`).Scan(&one); err != nil {
+		return fmt.Errorf("failed to query database: %w", err)
+	}
+	return nil
+}
+
+// CheckSchema confirms every migration has been applied and none are from a newer build
+func (db *DB) CheckSchema(ctx context.Context) error {
+	var version int
+	if err := db.conn.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if version != len(migrations) {
+		return fmt.Errorf("%w: at version %d, expected %d", ErrSchemaOutdated, version, len(migrations))
+	}
+	return nil
+}
+
+// CheckWrite takes the write lock the same way every write does, then
+// reports whether the last write transaction failed. An empty transaction
+// never touches the disk, so a full or read-only disk only shows up in the
+// writes sessions make, and the probe itself isn't recorded as one.
+func (db *DB) CheckWrite(ctx context.Context) error {
+	if err := db.runWrite(ctx, func(context.Context, *sql.Tx) error { return nil }, false); err != nil {
+		return fmt.Errorf("failed to start write: %w", err)
+	}
+	if err := db.writeHealth.last(); err != nil {
+		return fmt.Errorf("last write failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"modernc.org/sqlite"
+
+	"github.com/rayhanadev/2048/metrics"
+)
+
+// instrumentedConnector opens SQLite connections that record the latency
+// and errors of every statement, including prepared ones and those run in
+// transactions. Failed statements are logged with the logger in their
+// context, so they carry the session ID of the session that ran them.
+type instrumentedConnector struct {
+	dsn    string
+	driver *sqlite.Driver
+}
+
+func newInstrumentedConnector(dsn string) *instrumentedConnector {
+	return &instrumentedConnector{dsn: dsn, driver: &sqlite.Driver{}}
+}
+
+func (c *instrumentedConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{conn.(sqliteConn)}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqliteConn is the set of driver interfaces SQLite connections implement
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type instrumentedConn struct {
+	sqliteConn
+}
+
+// observe records how long a statement of the given kind took and whether it failed
+func observe(ctx context.Context, kind, query string, start time.Time, err error) {
+	// ErrSkip asks database/sql to retry another way, so nothing ran
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	metrics.DBQueryDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+	if err == nil {
+		return
+	}
+
+	metrics.DBErrors.WithLabelValues(kind).Inc()
+	logger := log.FromContext(ctx)
+	if ctx.Err() != nil {
+		// The caller gave up, usually because the session ended
+		logger.Debug("Database statement cancelled", "kind", kind, "query", compactQuery(query), "error", err)
+		return
+	}
+	logger.Error("Database statement failed", "kind", kind, "query", compactQuery(query), "error", err)
+}
+
+// compactQuery puts a query on one line for logging
+func compactQuery(query string) string {
+	const maxLength = 200
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > maxLength {
+		query = query[:maxLength] + "…"
+	}
+	return query
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := c.sqliteConn.ExecContext(ctx, query, args)
+	observe(ctx, "exec", query, start, err)
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.sqliteConn.QueryContext(ctx, query, args)
+	observe(ctx, "query", query, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.sqliteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{stmt.(sqliteStmt), query}, nil
+}
+
+// Prepare is required by driver.Conn; database/sql uses PrepareContext
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// sqliteStmt is the set of driver interfaces SQLite statements implement
+type sqliteStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+type instrumentedStmt struct {
+	sqliteStmt
+	query string
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := s.sqliteStmt.ExecContext(ctx, args)
+	observe(ctx, "exec", s.query, start, err)
+	return result, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.sqliteStmt.QueryContext(ctx, args)
+	observe(ctx, "query", s.query, start, err)
+	return rows, err
+}
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// pairingAlphabet avoids characters that are easy to confuse when typed
+const pairingAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const pairingCodeLength = 8
+
+// PairingCodeTTL is how long a pairing code stays valid
+const PairingCodeTTL = 10 * time.Minute
+
+var (
+	// ErrInvalidPairingCode is returned for unknown or expired pairing codes
+	ErrInvalidPairingCode = errors.New("pairing code is invalid or has expired")
+
+	// ErrKeyAlreadyLinked is returned when a key already belongs to a player
+	ErrKeyAlreadyLinked = errors.New("this key is already linked to an account")
+
+	// ErrKeyNotFound is returned when a key isn't linked to the given player
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrLastKey is returned when revoking would leave a player without any key
+	ErrLastKey = errors.New("cannot revoke the only key on an account")
+)
+
+// PlayerKey is an SSH public key linked to a player
+type PlayerKey struct {
+	Fingerprint string
+	PlayerID    int64
+	CreatedAt   time.Time
+	LastUsedAt  sql.NullTime
+}
+
+// CreatePairingCode generates a one-time code that links another key to a player.
+// Any earlier code for the same player is replaced.
+func (db *DB) CreatePairingCode(ctx context.Context, playerID int64) (string, time.Time, error) {
+	code, err := randomCode(pairingCodeLength)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().UTC().Add(PairingCodeTTL)
+
+	err = db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pairing_codes WHERE player_id = ?`, playerID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO pairing_codes (code, player_id, expires_at)
+			VALUES (?, ?, ?)
+		`, code, playerID, expiresAt)
+		return err
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return code, expiresAt, nil
+}
+
+// LinkKey attaches a new key to the player that issued the pairing code.
+// The code is consumed on success.
+func (db *DB) LinkKey(ctx context.Context, code, fingerprint string) (*Player, error) {
+	player := &Player{}
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var playerID int64
+		err := tx.QueryRowContext(ctx, `
+			SELECT player_id FROM pairing_codes
+			WHERE code = ? AND expires_at > ?
+		`, normalizeCode(code), time.Now().UTC()).Scan(&playerID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidPairingCode
+		}
+		if err != nil {
+			return err
+		}
+
+		var exists int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM player_keys WHERE fingerprint = ?
+		`, fingerprint).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrKeyAlreadyLinked
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO player_keys (fingerprint, player_id, last_used_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)
+		`, fingerprint, playerID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pairing_codes WHERE player_id = ?`, playerID); err != nil {
+			return err
+		}
+
+		return scanPlayer(tx.QueryRowContext(ctx, playerByIDQuery, playerID), player)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return player, nil
+}
+
+// PairingCodeOwner returns the player that issued a pairing code without consuming it
+func (db *DB) PairingCodeOwner(ctx context.Context, code string) (*Player, error) {
+	player := &Player{}
+	err := scanPlayer(db.conn.QueryRowContext(ctx, `
+		SELECT `+playerColumns+`
+		FROM pairing_codes c
+		JOIN players p ON p.id = c.player_id
+		WHERE c.code = ? AND c.expires_at > ?
+	`, normalizeCode(code), time.Now().UTC()), player)
+	if errors.Is(err, ErrPlayerNotFound) {
+		return nil, ErrInvalidPairingCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return player, nil
+}
+
+// GetPlayerKeys returns every key linked to a player, oldest first
+func (db *DB) GetPlayerKeys(ctx context.Context, playerID int64) ([]PlayerKey, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT fingerprint, player_id, created_at, last_used_at
+		FROM player_keys
+		WHERE player_id = ?
+		ORDER BY created_at, fingerprint
+	`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []PlayerKey
+	for rows.Next() {
+		var k PlayerKey
+		if err := rows.Scan(&k.Fingerprint, &k.PlayerID, &k.CreatedAt, &k.LastUsedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeKey unlinks a key from a player. A player's last key can't be revoked.
+func (db *DB) RevokeKey(ctx context.Context, playerID int64, fingerprint string) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM player_keys WHERE player_id = ?
+		`, playerID).Scan(&count); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM player_keys WHERE player_id = ? AND fingerprint = ?
+		`, playerID, fingerprint)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrKeyNotFound
+		}
+		if count <= 1 {
+			return ErrLastKey
+		}
+
+		// players.pubkey_fingerprint must keep pointing at a linked key
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE players
+			SET pubkey_fingerprint = (
+				SELECT fingerprint FROM player_keys
+				WHERE player_id = ?
+				ORDER BY created_at
+				LIMIT 1
+			)
+			WHERE id = ? AND pubkey_fingerprint = ?
+		`, playerID, playerID, fingerprint); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// TouchKey records that a key was just used to connect
+func (db *DB) TouchKey(ctx context.Context, fingerprint string) error {
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.StmtContext(ctx, db.stmts.touchKey).ExecContext(ctx, fingerprint)
+		return err
+	})
+}
+
+func randomCode(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = pairingAlphabet[int(b)%len(pairingAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// normalizeCode accepts codes typed in lower case or with separators
+func normalizeCode(code string) string {
+	out := make([]byte, 0, len(code))
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			out = append(out, c-'a'+'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"time"
 )
 
@@ -13,9 +14,16 @@ type LeaderboardEntry struct {
 	CreatedAt time.Time
 }
 
-// GetLeaderboard returns the top scores globally
-func (db *DB) GetLeaderboard(limit int) ([]LeaderboardEntry, error) {
-	rows, err := db.conn.Query(`
+// GetLeaderboard returns the top scores of a season, or of all time when
+// season is nil. Hidden players are left out. Archived seasons return their
+// frozen standings.
+func (db *DB) GetLeaderboard(ctx context.Context, season *Season, limit int) ([]LeaderboardEntry, error) {
+	if season != nil && season.Archived() {
+		return db.getSeasonStandings(ctx, season.ID, limit)
+	}
+
+	inSeason, args := seasonFilter("s.created_at", season)
+	rows, err := db.conn.QueryContext(ctx, `
 		SELECT 
 			p.username,
 			s.score,
@@ -23,9 +31,10 @@ func (db *DB) GetLeaderboard(limit int) ([]LeaderboardEntry, error) {
 			s.created_at
 		FROM scores s
 		JOIN players p ON s.player_id = p.id
+		WHERE p.hidden = 0 AND `+inSeason+`
 		ORDER BY s.score DESC
 		LIMIT ?
-	`, limit)
+	`, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -46,18 +55,76 @@ func (db *DB) GetLeaderboard(limit int) ([]LeaderboardEntry, error) {
 	return entries, rows.Err()
 }
 
-// GetPlayerRank returns the rank of a player's best score
-func (db *DB) GetPlayerRank(playerID int64) (int, error) {
+// getSeasonStandings returns the standings frozen when a season was archived.
+// Ranks are kept as frozen even if players have since hidden or deleted themselves.
+func (db *DB) getSeasonStandings(ctx context.Context, seasonID int64, limit int) ([]LeaderboardEntry, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT st.rank, st.username, st.score, st.max_tile, st.created_at
+		FROM season_standings st
+		JOIN players p ON st.player_id = p.id
+		WHERE st.season_id = ? AND p.hidden = 0
+		ORDER BY st.rank
+		LIMIT ?
+	`, seasonID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []LeaderboardEntry
+	for rows.Next() {
+		var e LeaderboardEntry
+		if err := rows.Scan(&e.Rank, &e.Username, &e.Score, &e.MaxTile, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetPlayerRank returns the rank of a player's best score among visible
+// players in a season, or of all time when season is nil
+func (db *DB) GetPlayerRank(ctx context.Context, playerID int64, season *Season) (int, error) {
+	inSeason, args := seasonFilter("s.created_at", season)
+	ownInSeason, ownArgs := seasonFilter("created_at", season)
+	args = append(args, playerID)
+	args = append(args, ownArgs...)
+
 	var rank int
-	err := db.conn.QueryRow(`
+	err := db.conn.QueryRowContext(ctx, `
 		SELECT COUNT(*) + 1
-		FROM scores
-		WHERE score > (
+		FROM scores s
+		JOIN players p ON s.player_id = p.id
+		WHERE p.hidden = 0 AND `+inSeason+` AND s.score > (
 			SELECT COALESCE(MAX(score), 0)
 			FROM scores
-			WHERE player_id = ?
+			WHERE player_id = ? AND `+ownInSeason+`
 		)
-	`, playerID).Scan(&rank)
+	`, args...).Scan(&rank)
 
 	return rank, err
 }
+
+// seasonFilter returns a condition on column matching timestamps within a
+// season, with its arguments. A nil season matches everything.
+func seasonFilter(column string, season *Season) (string, []any) {
+	if season == nil {
+		return "1", nil
+	}
+	return column + " >= ? AND " + column + " < ?", []any{
+		season.StartsAt.UTC().Format(timestampFormat),
+		season.EndsAt.UTC().Format(timestampFormat),
+	}
+}
+
+// seasonScores returns a query selecting player_id, score, max_tile and
+// created_at for every score counted in a season, or of all time when season
+// is nil, with its arguments. Archived seasons select the frozen standings.
+func seasonScores(season *Season) (string, []any) {
+	if season != nil && season.Archived() {
+		return `SELECT player_id, score, max_tile, created_at FROM season_standings WHERE season_id = ?`, []any{season.ID}
+	}
+	inSeason, args := seasonFilter("created_at", season)
+	return `SELECT player_id, score, max_tile, created_at FROM scores WHERE ` + inSeason, args
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrMergeSamePlayer is returned when asked to merge a player into itself
+var ErrMergeSamePlayer = errors.New("cannot merge a player into itself")
+
+// MergeResult summarizes what a merge moved from one player to another
+type MergeResult struct {
+	Kept         Player
+	Merged       Player
+	Username     string
+	Scores       int64
+	Keys         int64
+	Achievements int64
+	Renames      int64
+}
+
+// MergePlayers folds mergeID's history into keepID and deletes mergeID.
+// The kept player ends up with username, which must be either player's current name.
+// With dryRun set, the merge is performed and rolled back so callers can preview it.
+func (db *DB) MergePlayers(ctx context.Context, keepID, mergeID int64, username, actor string, dryRun bool) (*MergeResult, error) {
+	if keepID == mergeID {
+		return nil, ErrMergeSamePlayer
+	}
+
+	res := &MergeResult{}
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if err := scanPlayer(tx.QueryRowContext(ctx, playerByIDQuery, keepID), &res.Kept); err != nil {
+			return err
+		}
+		if err := scanPlayer(tx.QueryRowContext(ctx, playerByIDQuery, mergeID), &res.Merged); err != nil {
+			return err
+		}
+
+		if username == "" {
+			username = res.Kept.Username
+		}
+		if username != res.Kept.Username && username != res.Merged.Username {
+			return fmt.Errorf("username must be %q or %q", res.Kept.Username, res.Merged.Username)
+		}
+		res.Username = username
+
+		if res.Scores, err = execCount(ctx, tx, `UPDATE scores SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move scores: %w", err)
+		}
+
+		// Pruned totals for the same score and tile are added together
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO pruned_scores (player_id, score, max_tile, games, moves, duration_seconds)
+			SELECT ?, score, max_tile, games, moves, duration_seconds FROM pruned_scores WHERE player_id = ? AND true
+			ON CONFLICT (player_id, score, max_tile) DO UPDATE SET
+				games = games + excluded.games,
+				moves = moves + excluded.moves,
+				duration_seconds = duration_seconds + excluded.duration_seconds
+		`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move pruned scores: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pruned_scores WHERE player_id = ?`, mergeID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE OR IGNORE pruned_days SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move pruned days: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pruned_days WHERE player_id = ?`, mergeID); err != nil {
+			return err
+		}
+
+		if res.Keys, err = execCount(ctx, tx, `UPDATE player_keys SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move keys: %w", err)
+		}
+
+		// Keep the earliest unlock when both players have the same achievement
+		if res.Achievements, err = execCount(ctx, tx, `
+			INSERT INTO achievements (player_id, achievement_id, unlocked_at)
+			SELECT ?, achievement_id, unlocked_at FROM achievements WHERE player_id = ? AND true
+			ON CONFLICT (player_id, achievement_id)
+			DO UPDATE SET unlocked_at = MIN(unlocked_at, excluded.unlocked_at)
+		`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move achievements: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM achievements WHERE player_id = ?`, mergeID); err != nil {
+			return err
+		}
+
+		if res.Renames, err = execCount(ctx, tx, `UPDATE username_history SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move rename history: %w", err)
+		}
+
+		// Follows that would be duplicates or a self-follow are left behind and deleted
+		for _, column := range []string{"follower_id", "followee_id"} {
+			if _, err := tx.ExecContext(ctx, `UPDATE OR IGNORE follows SET `+column+` = ? WHERE `+column+` = ?`, keepID, mergeID); err != nil {
+				return fmt.Errorf("failed to move follows: %w", err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM follows WHERE ? IN (follower_id, followee_id)`, mergeID); err != nil {
+			return err
+		}
+
+		// The kept player stays in their own team if they have one
+		if _, err := tx.ExecContext(ctx, `UPDATE OR IGNORE team_members SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move team membership: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM team_members WHERE player_id = ?`, mergeID); err != nil {
+			return err
+		}
+		if err := deleteEmptyTeams(ctx, tx); err != nil {
+			return err
+		}
+
+		// Archived standings keep the username they were frozen with
+		if _, err := tx.ExecContext(ctx, `UPDATE season_standings SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move season standings: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE tournament_results SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move tournament results: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE tournament_attempts SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move tournament attempts: %w", err)
+		}
+
+		// The kept player's own saved game wins over the merged one
+		if _, err := tx.ExecContext(ctx, `UPDATE OR IGNORE saved_games SET player_id = ? WHERE player_id = ?`, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move saved game: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM saved_games WHERE player_id = ?`, mergeID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pairing_codes WHERE player_id IN (?, ?)`, keepID, mergeID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM players WHERE id = ?`, mergeID); err != nil {
+			return fmt.Errorf("failed to delete merged player: %w", err)
+		}
+
+		// The merged row is gone, so taking its name can no longer conflict
+		if username != res.Kept.Username {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE players SET username = ?, username_key = ? WHERE id = ?
+			`, username, usernameKey(username), keepID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO username_history (player_id, old_username, new_username)
+				VALUES (?, ?, ?)
+			`, keepID, res.Kept.Username, username); err != nil {
+				return err
+			}
+		}
+
+		if err := checkForeignKeys(ctx, tx, playerTableNames()...); err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, actor, "merge_players", keepID, map[string]any{
+			"merged_id":       mergeID,
+			"merged_username": res.Merged.Username,
+			"kept_username":   res.Kept.Username,
+			"username":        username,
+			"scores":          res.Scores,
+			"keys":            res.Keys,
+			"achievements":    res.Achievements,
+		}); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+	return res, nil
+}
+
+func execCount(ctx context.Context, e execer, query string, args ...any) (int64, error) {
+	result, err := e.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// checkForeignKeys fails if any row of tables references a missing parent.
+// Only tables are checked, so an orphan left elsewhere by an unrelated
+// problem doesn't block the write. It runs regardless of whether the
+// connection has foreign key enforcement on.
+func checkForeignKeys(ctx context.Context, tx *sql.Tx, tables ...string) error {
+	for _, table := range tables {
+		if err := checkTableForeignKeys(ctx, tx, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTableForeignKeys(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT "table", rowid, parent, fkid FROM pragma_foreign_key_check(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var table string
+		var rowid sql.NullInt64
+		var parent string
+		var fkid int
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("foreign key violation: %s row %d references missing %s", table, rowid.Int64, parent)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newPlayer creates a player whose key fingerprint is "fp-" plus their name
+func newPlayer(t *testing.T, db *DB, username string) *Player {
+	t.Helper()
+	p, err := db.CreatePlayer(context.Background(), "fp-"+username, username)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", username, err)
+	}
+	return p
+}
+
+// count returns the number of rows query selects
+func count(t *testing.T, db *DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.conn.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM (`+query+`)`, args...).Scan(&n); err != nil {
+		t.Fatalf("failed to count %q: %v", query, err)
+	}
+	return n
+}
+
+// exec runs a statement the tests need to set up rows directly
+func exec(t *testing.T, db *DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.conn.ExecContext(context.Background(), query, args...); err != nil {
+		t.Fatalf("failed to run %q: %v", query, err)
+	}
+}
+
+func TestMergePlayersUsername(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     string
+		wantErr  bool
+	}{
+		{name: "kept name by default", username: "", want: "alice"},
+		{name: "merged name", username: "bob", want: "bob"},
+		{name: "merged name in another case", username: "BOB", wantErr: true},
+		{name: "unrelated name", username: "carol", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, DefaultOptions())
+			ctx := context.Background()
+			alice := newPlayer(t, db, "alice")
+			bob := newPlayer(t, db, "bob")
+
+			res, err := db.MergePlayers(ctx, alice.ID, bob.ID, tt.username, "test", false)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("merge succeeded, want an error")
+				}
+				if _, err := db.GetPlayerByID(ctx, bob.ID); err != nil {
+					t.Errorf("merged player is gone after a failed merge: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("merge failed: %v", err)
+			}
+
+			if res.Username != tt.want {
+				t.Errorf("result username is %q, want %q", res.Username, tt.want)
+			}
+			kept, err := db.GetPlayerByID(ctx, alice.ID)
+			if err != nil {
+				t.Fatalf("kept player is gone: %v", err)
+			}
+			if kept.Username != tt.want {
+				t.Errorf("kept player is named %q, want %q", kept.Username, tt.want)
+			}
+			if _, err := db.GetPlayerByID(ctx, bob.ID); !errors.Is(err, ErrPlayerNotFound) {
+				t.Errorf("merged player lookup returned %v, want %v", err, ErrPlayerNotFound)
+			}
+
+			renames := 0
+			if tt.want != "alice" {
+				renames = 1
+			}
+			if n := count(t, db, `SELECT 1 FROM username_history WHERE player_id = ?`, alice.ID); n != renames {
+				t.Errorf("kept player has %d renames, want %d", n, renames)
+			}
+		})
+	}
+}
+
+func TestMergePlayersSamePlayer(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	alice := newPlayer(t, db, "alice")
+
+	if _, err := db.MergePlayers(context.Background(), alice.ID, alice.ID, "", "test", false); !errors.Is(err, ErrMergeSamePlayer) {
+		t.Errorf("merge returned %v, want %v", err, ErrMergeSamePlayer)
+	}
+}
+
+func TestMergePlayersMovesHistory(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+	carol := newPlayer(t, db, "carol")
+
+	if err := db.SaveScore(ctx, alice.ID, 100, 8, 10, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveScore(ctx, bob.ID, 200, 16, 20, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UnlockAchievement(ctx, alice.ID, "first_game"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UnlockAchievement(ctx, bob.ID, "first_game"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UnlockAchievement(ctx, bob.ID, "tile_16"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveGame(ctx, bob.ID, "bob's game"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := db.MergePlayers(ctx, alice.ID, bob.ID, "", "test", false)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if res.Scores != 1 || res.Keys != 1 {
+		t.Errorf("merge moved %d scores and %d keys, want 1 and 1", res.Scores, res.Keys)
+	}
+
+	if n := count(t, db, `SELECT 1 FROM scores WHERE player_id = ?`, alice.ID); n != 2 {
+		t.Errorf("kept player has %d scores, want 2", n)
+	}
+	for _, fp := range []string{"fp-alice", "fp-bob"} {
+		p, err := db.GetPlayerByFingerprint(ctx, fp)
+		if err != nil || p.ID != alice.ID {
+			t.Errorf("key %s belongs to %v (%v), want the kept player", fp, p, err)
+		}
+	}
+	if n := count(t, db, `SELECT 1 FROM achievements WHERE player_id = ?`, alice.ID); n != 2 {
+		t.Errorf("kept player has %d achievements, want 2", n)
+	}
+	if state, err := db.TakeSavedGame(ctx, alice.ID); err != nil || state != "bob's game" {
+		t.Errorf("kept player's saved game is %q (%v), want the merged player's", state, err)
+	}
+
+	// Nothing of the merged player is left behind
+	for _, table := range playerTableNames() {
+		column := "player_id"
+		for _, pt := range playerTables {
+			if pt.table == table {
+				column = pt.column
+			}
+		}
+		if n := count(t, db, `SELECT 1 FROM `+table+` WHERE `+column+` = ?`, bob.ID); n != 0 {
+			t.Errorf("%s still has %d rows of the merged player", table, n)
+		}
+	}
+	if _, err := db.GetPlayerByID(ctx, carol.ID); err != nil {
+		t.Errorf("unrelated player is gone: %v", err)
+	}
+}
+
+func TestMergePlayersFollows(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+	carol := newPlayer(t, db, "carol")
+
+	// alice and bob follow each other, carol follows both and bob follows carol
+	for _, f := range []struct {
+		follower *Player
+		followee string
+	}{
+		{alice, "bob"}, {bob, "alice"}, {carol, "alice"}, {carol, "bob"}, {bob, "carol"},
+	} {
+		if _, err := db.Follow(ctx, f.follower.ID, f.followee); err != nil {
+			t.Fatalf("failed to follow %s: %v", f.followee, err)
+		}
+	}
+
+	if _, err := db.MergePlayers(ctx, alice.ID, bob.ID, "", "test", false); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	if n := count(t, db, `SELECT 1 FROM follows WHERE follower_id = followee_id`); n != 0 {
+		t.Errorf("%d self-follows left", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?`, carol.ID, alice.ID); n != 1 {
+		t.Errorf("carol follows the kept player %d times, want once", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?`, alice.ID, carol.ID); n != 1 {
+		t.Errorf("kept player follows carol %d times, want once", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM follows`); n != 2 {
+		t.Errorf("%d follows left, want 2", n)
+	}
+}
+
+func TestMergePlayersTeams(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+	carol := newPlayer(t, db, "carol")
+
+	aliceTeam, err := db.CreateTeam(ctx, alice.ID, "sliders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bobTeam, err := db.CreateTeam(ctx, bob.ID, "mergers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	carolTeam, err := db.CreateTeam(ctx, carol.ID, "stackers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.MergePlayers(ctx, alice.ID, bob.ID, "", "test", false); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	team, err := db.GetPlayerTeam(ctx, alice.ID)
+	if err != nil || team.ID != aliceTeam.ID {
+		t.Errorf("kept player is in %v (%v), want their own team", team, err)
+	}
+	if n := count(t, db, `SELECT 1 FROM teams WHERE id = ?`, bobTeam.ID); n != 0 {
+		t.Error("the merged player's emptied team still exists")
+	}
+	if n := count(t, db, `SELECT 1 FROM teams WHERE id = ?`, carolTeam.ID); n != 1 {
+		t.Error("an unrelated team was deleted")
+	}
+}
+
+func TestMergePlayersFoldsPrunedTotals(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+
+	exec(t, db, `
+		INSERT INTO pruned_scores (player_id, score, max_tile, games, moves, duration_seconds) VALUES
+		(?, 100, 8, 2, 20, 60), (?, 100, 8, 3, 30, 90), (?, 300, 32, 1, 40, 120)
+	`, alice.ID, bob.ID, bob.ID)
+	exec(t, db, `
+		INSERT INTO pruned_days (player_id, day) VALUES (?, '2024-01-01'), (?, '2024-01-01'), (?, '2024-01-02')
+	`, alice.ID, bob.ID, bob.ID)
+
+	if _, err := db.MergePlayers(ctx, alice.ID, bob.ID, "", "test", false); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	var games, moves, seconds int
+	if err := db.conn.QueryRowContext(ctx, `
+		SELECT games, moves, duration_seconds FROM pruned_scores WHERE player_id = ? AND score = 100 AND max_tile = 8
+	`, alice.ID).Scan(&games, &moves, &seconds); err != nil {
+		t.Fatalf("failed to read folded totals: %v", err)
+	}
+	if games != 5 || moves != 50 || seconds != 150 {
+		t.Errorf("folded totals are %d games, %d moves, %ds, want 5, 50, 150s", games, moves, seconds)
+	}
+	if n := count(t, db, `SELECT 1 FROM pruned_scores WHERE player_id = ?`, alice.ID); n != 2 {
+		t.Errorf("kept player has %d pruned rows, want 2", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM pruned_days WHERE player_id = ?`, alice.ID); n != 2 {
+		t.Errorf("kept player has %d pruned days, want 2", n)
+	}
+
+	stats, err := db.GetPlayerStats(ctx, alice.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.GamesPlayed != 6 || stats.BestScore != 300 {
+		t.Errorf("stats show %d games and a best of %d, want 6 and 300", stats.GamesPlayed, stats.BestScore)
+	}
+}
+
+func TestMergePlayersDryRun(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+	if err := db.SaveScore(ctx, bob.ID, 200, 16, 20, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := db.MergePlayers(ctx, alice.ID, bob.ID, "bob", "test", true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if res.Scores != 1 || res.Username != "bob" {
+		t.Errorf("dry run reports %d scores and username %q, want 1 and %q", res.Scores, res.Username, "bob")
+	}
+
+	if _, err := db.GetPlayerByID(ctx, bob.ID); err != nil {
+		t.Errorf("merged player is gone after a dry run: %v", err)
+	}
+	if n := count(t, db, `SELECT 1 FROM scores WHERE player_id = ?`, bob.ID); n != 1 {
+		t.Errorf("merged player has %d scores after a dry run, want 1", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM audit_log`); n != 0 {
+		t.Errorf("dry run left %d audit entries", n)
+	}
+}
+
+func TestMergePlayersIgnoresUnrelatedOrphans(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+
+	// An orphaned round elsewhere, left by something outside this merge
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO tournament_rounds (tournament_id, round, seed) VALUES (999, 1, 1)`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			t.Fatalf("failed to run %q: %v", query, err)
+		}
+	}
+	conn.Close()
+
+	if _, err := db.MergePlayers(ctx, alice.ID, bob.ID, "", "test", false); err != nil {
+		t.Errorf("merge failed: %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration is a single schema change. Data fixes that SQL alone can't
+// express go in apply, which runs after schema in the same transaction.
+type migration struct {
+	schema string
+	apply  func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrations holds every schema change in the order it was introduced.
+// The number of applied migrations is tracked in SQLite's user_version
+// pragma, so entries must only ever be appended, never edited.
+var migrations = []migration{
+	// 1: initial schema
+	{schema: `
+	CREATE TABLE IF NOT EXISTS players (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pubkey_fingerprint TEXT UNIQUE NOT NULL,
+		username TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS scores (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_id INTEGER NOT NULL,
+		score INTEGER NOT NULL,
+		max_tile INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (player_id) REFERENCES players(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_scores_score ON scores(score DESC);
+	CREATE INDEX IF NOT EXISTS idx_players_fingerprint ON players(pubkey_fingerprint);
+	`},
+
+	// 2: per-game move count and play time for player statistics
+	{schema: `
+	ALTER TABLE scores ADD COLUMN moves INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE scores ADD COLUMN duration_seconds INTEGER NOT NULL DEFAULT 0;
+
+	CREATE INDEX IF NOT EXISTS idx_scores_player ON scores(player_id, created_at);
+	`},
+
+	// 3: unlocked achievements
+	{schema: `
+	CREATE TABLE IF NOT EXISTS achievements (
+		player_id INTEGER NOT NULL,
+		achievement_id TEXT NOT NULL,
+		unlocked_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (player_id, achievement_id),
+		FOREIGN KEY (player_id) REFERENCES players(id)
+	);
+	`},
+
+	// 4: case-insensitive unique usernames and rename history
+	{schema: `
+	ALTER TABLE players ADD COLUMN username_key TEXT;
+
+	CREATE TABLE IF NOT EXISTS username_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		player_id INTEGER NOT NULL,
+		old_username TEXT NOT NULL,
+		new_username TEXT NOT NULL,
+		changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (player_id) REFERENCES players(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_username_history_player ON username_history(player_id, changed_at);
+	`, apply: dedupeUsernames},
+
+	// 5: multiple SSH keys per player and one-time pairing codes
+	{schema: `
+	CREATE TABLE IF NOT EXISTS player_keys (
+		fingerprint TEXT PRIMARY KEY,
+		player_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME,
+		FOREIGN KEY (player_id) REFERENCES players(id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_player_keys_player ON player_keys(player_id);
+
+	INSERT OR IGNORE INTO player_keys (fingerprint, player_id, created_at)
+	SELECT pubkey_fingerprint, id, created_at FROM players;
+
+	CREATE TABLE IF NOT EXISTS pairing_codes (
+		code TEXT PRIMARY KEY,
+		player_id INTEGER NOT NULL,
+		expires_at DATETIME NOT NULL,
+		FOREIGN KEY (player_id) REFERENCES players(id)
+	);
+	`},
+
+	// 6: audit trail for account-level operations
+	{schema: `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		player_id INTEGER,
+		details TEXT NOT NULL DEFAULT '{}',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_player ON audit_log(player_id, created_at);
+	`},
+
+	// 7: player privacy settings
+	{schema: `
+	ALTER TABLE players ADD COLUMN hidden INTEGER NOT NULL DEFAULT 0;
+	`},
+
+	// 8: leaderboard seasons and archived standings
+	{schema: `
+	CREATE TABLE IF NOT EXISTS seasons (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		starts_at DATETIME NOT NULL,
+		ends_at DATETIME NOT NULL,
+		archived_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_seasons_starts_at ON seasons(starts_at);
+
+	CREATE TABLE IF NOT EXISTS season_standings (
+		season_id INTEGER NOT NULL REFERENCES seasons(id),
+		rank INTEGER NOT NULL,
+		player_id INTEGER NOT NULL REFERENCES players(id),
+		username TEXT NOT NULL,
+		score INTEGER NOT NULL,
+		max_tile INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (season_id, rank)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_season_standings_player ON season_standings(player_id);
+	CREATE INDEX IF NOT EXISTS idx_scores_created_at ON scores(created_at);
+	`},
+
+	// 9: follows between players
+	{schema: `
+	CREATE TABLE IF NOT EXISTS follows (
+		follower_id INTEGER NOT NULL REFERENCES players(id),
+		followee_id INTEGER NOT NULL REFERENCES players(id),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (follower_id, followee_id),
+		CHECK (follower_id != followee_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id);
+	`},
+
+	// 10: teams and their members
+	{schema: `
+	CREATE TABLE IF NOT EXISTS teams (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL COLLATE NOCASE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS team_members (
+		player_id INTEGER PRIMARY KEY REFERENCES players(id),
+		team_id INTEGER NOT NULL REFERENCES teams(id),
+		joined_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_team_members_team ON team_members(team_id);
+	`},
+
+	// 11: tournaments with seeded rounds, attempts and final results
+	{schema: `
+	CREATE TABLE IF NOT EXISTS tournaments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT UNIQUE NOT NULL COLLATE NOCASE,
+		starts_at DATETIME NOT NULL,
+		ends_at DATETIME NOT NULL,
+		attempts INTEGER NOT NULL,
+		opened_at DATETIME,
+		finalized_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS tournament_rounds (
+		tournament_id INTEGER NOT NULL REFERENCES tournaments(id),
+		round INTEGER NOT NULL,
+		seed INTEGER NOT NULL,
+		PRIMARY KEY (tournament_id, round)
+	);
+
+	CREATE TABLE IF NOT EXISTS tournament_attempts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tournament_id INTEGER NOT NULL REFERENCES tournaments(id),
+		round INTEGER NOT NULL,
+		player_id INTEGER NOT NULL REFERENCES players(id),
+		score INTEGER NOT NULL DEFAULT 0,
+		max_tile INTEGER NOT NULL DEFAULT 0,
+		moves INTEGER NOT NULL DEFAULT 0,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tournament_attempts_player ON tournament_attempts(tournament_id, player_id, round);
+	CREATE INDEX IF NOT EXISTS idx_tournament_attempts_player_id ON tournament_attempts(player_id);
+
+	CREATE TABLE IF NOT EXISTS tournament_results (
+		tournament_id INTEGER NOT NULL REFERENCES tournaments(id),
+		rank INTEGER NOT NULL,
+		player_id INTEGER NOT NULL REFERENCES players(id),
+		username TEXT NOT NULL,
+		total INTEGER NOT NULL,
+		round_scores TEXT NOT NULL,
+		PRIMARY KEY (tournament_id, rank)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tournament_results_player ON tournament_results(player_id);
+	`},
+
+	// 12: games saved when a session is closed mid-game
+	{schema: `
+	CREATE TABLE IF NOT EXISTS saved_games (
+		player_id INTEGER PRIMARY KEY REFERENCES players(id),
+		state TEXT NOT NULL,
+		saved_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`},
+
+	// 13: player bans
+	{schema: `
+	ALTER TABLE players ADD COLUMN banned_at DATETIME;
+	ALTER TABLE players ADD COLUMN ban_reason TEXT NOT NULL DEFAULT '';
+	`},
+
+	// 14: key, address and range bans
+	{schema: `
+	CREATE TABLE IF NOT EXISTS bans (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL CHECK (kind IN ('fingerprint', 'ip', 'cidr')),
+		value TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		expires_at DATETIME,
+		created_by TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (kind, value)
+	);
+	`},
+
+	// 15: guest runs waiting to be claimed
+	{schema: `
+	CREATE TABLE IF NOT EXISTS guest_runs (
+		code TEXT PRIMARY KEY,
+		score INTEGER NOT NULL,
+		max_tile INTEGER NOT NULL,
+		moves INTEGER NOT NULL,
+		duration_seconds INTEGER NOT NULL,
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`},
+
+	// 16: totals of pruned scores, so stats and achievements survive pruning
+	{schema: `
+	CREATE TABLE IF NOT EXISTS pruned_scores (
+		player_id INTEGER NOT NULL REFERENCES players(id),
+		score INTEGER NOT NULL,
+		max_tile INTEGER NOT NULL,
+		games INTEGER NOT NULL,
+		moves INTEGER NOT NULL,
+		duration_seconds INTEGER NOT NULL,
+		PRIMARY KEY (player_id, score, max_tile)
+	);
+
+	CREATE TABLE IF NOT EXISTS pruned_days (
+		player_id INTEGER NOT NULL REFERENCES players(id),
+		day TEXT NOT NULL,
+		PRIMARY KEY (player_id, day)
+	);
+	`},
+}
+
+// migrate brings the database schema up to date
+func (db *DB) migrate(ctx context.Context) error {
+	var version int
+	if err := db.conn.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, migrations[i].schema); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		if apply := migrations[i].apply; apply != nil {
+			if err := apply(ctx, tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+		}
+
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"time"
@@ -12,23 +13,34 @@ type Player struct {
 	PubkeyFingerprint string
 	Username          string
 	CreatedAt         time.Time
+	// Hidden players are left off public leaderboards
+	Hidden bool
+	// Banned players can't connect
+	BannedAt  sql.NullTime
+	BanReason string
 }
 
 // ErrPlayerNotFound is returned when a player doesn't exist
 var ErrPlayerNotFound = errors.New("player not found")
 
-// GetPlayerByFingerprint retrieves a player by their SSH public key fingerprint
-func (db *DB) GetPlayerByFingerprint(fingerprint string) (*Player, error) {
-	player := &Player{}
-	err := db.conn.QueryRow(`
-		SELECT id, pubkey_fingerprint, username, created_at
-		FROM players
-		WHERE pubkey_fingerprint = ?
-	`, fingerprint).Scan(&player.ID, &player.PubkeyFingerprint, &player.Username, &player.CreatedAt)
+// playerColumns are the players columns read by scanPlayer, qualified with the alias p
+const playerColumns = `p.id, p.pubkey_fingerprint, p.username, p.created_at, p.hidden, p.banned_at, p.ban_reason`
+
+const playerByIDQuery = `SELECT ` + playerColumns + ` FROM players p WHERE p.id = ?`
 
+// scanPlayer reads a row selected with playerColumns
+func scanPlayer(row *sql.Row, p *Player) error {
+	err := row.Scan(&p.ID, &p.PubkeyFingerprint, &p.Username, &p.CreatedAt, &p.Hidden, &p.BannedAt, &p.BanReason)
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, ErrPlayerNotFound
+		return ErrPlayerNotFound
 	}
+	return err
+}
+
+// GetPlayerByFingerprint retrieves a player by any of their linked SSH key fingerprints
+func (db *DB) GetPlayerByFingerprint(ctx context.Context, fingerprint string) (*Player, error) {
+	player := &Player{}
+	err := scanPlayer(db.stmts.playerByFingerprint.QueryRowContext(ctx, fingerprint), player)
 	if err != nil {
 		return nil, err
 	}
@@ -36,17 +48,59 @@ func (db *DB) GetPlayerByFingerprint(fingerprint string) (*Player, error) {
 	return player, nil
 }
 
-// CreatePlayer creates a new player record
-func (db *DB) CreatePlayer(fingerprint, username string) (*Player, error) {
-	result, err := db.conn.Exec(`
-		INSERT INTO players (pubkey_fingerprint, username)
-		VALUES (?, ?)
-	`, fingerprint, username)
+// GetPlayerByID retrieves a player by ID
+func (db *DB) GetPlayerByID(ctx context.Context, id int64) (*Player, error) {
+	player := &Player{}
+	if err := scanPlayer(db.conn.QueryRowContext(ctx, playerByIDQuery, id), player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+// GetPlayerByUsername retrieves a player by username, ignoring case
+func (db *DB) GetPlayerByUsername(ctx context.Context, username string) (*Player, error) {
+	player := &Player{}
+	err := scanPlayer(db.conn.QueryRowContext(ctx, `
+		SELECT `+playerColumns+`
+		FROM players p
+		WHERE p.username_key = ?
+	`, usernameKey(username)), player)
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
+	return player, nil
+}
+
+// CreatePlayer creates a new player record.
+// The username is validated and must be unique regardless of case.
+func (db *DB) CreatePlayer(ctx context.Context, fingerprint, username string) (*Player, error) {
+	if err := db.ValidateUsername(username); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO players (pubkey_fingerprint, username, username_key)
+			VALUES (?, ?, ?)
+		`, fingerprint, username, usernameKey(username))
+		if isUsernameConflict(err) {
+			return ErrUsernameTaken
+		}
+		if err != nil {
+			return err
+		}
+
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO player_keys (fingerprint, player_id) VALUES (?, ?)
+		`, fingerprint, id)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -59,20 +113,79 @@ func (db *DB) CreatePlayer(fingerprint, username string) (*Player, error) {
 	}, nil
 }
 
-// UpdateUsername updates a player's username
-func (db *DB) UpdateUsername(playerID int64, username string) error {
-	_, err := db.conn.Exec(`
-		UPDATE players SET username = ? WHERE id = ?
-	`, username, playerID)
-	return err
+// UpdateUsername renames a player and records the change in their history.
+// Renames are subject to validation, uniqueness and the rename cooldown.
+func (db *DB) UpdateUsername(ctx context.Context, playerID int64, username string) error {
+	if err := db.ValidateUsername(username); err != nil {
+		return err
+	}
+
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// Checked in the transaction so two renames can't both pass it
+		next, err := db.nextRenameAt(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if !next.IsZero() {
+			return ErrRenameCooldown
+		}
+
+		_, err = renamePlayer(ctx, tx, playerID, username)
+		return err
+	})
+}
+
+// renamePlayer changes a player's username and records the change in their
+// history, returning the old username
+func renamePlayer(ctx context.Context, tx *sql.Tx, playerID int64, username string) (string, error) {
+	var old string
+	if err := tx.QueryRowContext(ctx, `SELECT username FROM players WHERE id = ?`, playerID).Scan(&old); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrPlayerNotFound
+		}
+		return "", err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE players SET username = ?, username_key = ? WHERE id = ?
+	`, username, usernameKey(username), playerID)
+	if isUsernameConflict(err) {
+		return "", ErrUsernameTaken
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO username_history (player_id, old_username, new_username)
+		VALUES (?, ?, ?)
+	`, playerID, old, username); err != nil {
+		return "", err
+	}
+
+	return old, nil
+}
+
+// writePlayer runs fn like write once it has checked that the player still
+// exists, so a session whose player was deleted or merged into another gets
+// ErrPlayerNotFound instead of writing with a stale ID
+func (db *DB) writePlayer(ctx context.Context, playerID int64, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM players WHERE id = ?)`, playerID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrPlayerNotFound
+		}
+		return fn(ctx, tx)
+	})
 }
 
 // GetPlayerBestScore returns the highest score for a player
-func (db *DB) GetPlayerBestScore(playerID int64) (int, error) {
+func (db *DB) GetPlayerBestScore(ctx context.Context, playerID int64) (int, error) {
 	var score sql.NullInt64
-	err := db.conn.QueryRow(`
-		SELECT MAX(score) FROM scores WHERE player_id = ?
-	`, playerID).Scan(&score)
+	err := db.stmts.playerBestScore.QueryRowContext(ctx, playerID).Scan(&score)
 
 	if err != nil {
 		return 0, err
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// playerTables maps every table holding a player's data to its player column,
+// children before parents so deletes in this order satisfy foreign keys.
+// Rows marked others belong to other players and are deleted but not exported.
+var playerTables = []struct {
+	table  string
+	column string
+	others bool
+}{
+	{"follows", "followee_id", true},
+	{"follows", "follower_id", false},
+	{"team_members", "player_id", false},
+	{"season_standings", "player_id", false},
+	{"tournament_results", "player_id", false},
+	{"tournament_attempts", "player_id", false},
+	{"saved_games", "player_id", false},
+	{"achievements", "player_id", false},
+	{"scores", "player_id", false},
+	{"pruned_scores", "player_id", false},
+	{"pruned_days", "player_id", false},
+	{"username_history", "player_id", false},
+	{"pairing_codes", "player_id", false},
+	{"player_keys", "player_id", false},
+	{"players", "id", false},
+}
+
+// playerTableNames returns each table in playerTables once
+func playerTableNames() []string {
+	var names []string
+	for _, t := range playerTables {
+		if !slices.Contains(names, t.table) {
+			names = append(names, t.table)
+		}
+	}
+	return names
+}
+
+// DeleteResult counts the rows removed when a player deleted their account
+type DeleteResult struct {
+	Player       Player
+	Scores       int64
+	Keys         int64
+	Achievements int64
+	Renames      int64
+}
+
+// SetHidden shows or hides a player on public leaderboards
+func (db *DB) SetHidden(ctx context.Context, playerID int64, hidden bool) error {
+	return db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		n, err := execCount(ctx, tx, `UPDATE players SET hidden = ? WHERE id = ?`, hidden, playerID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrPlayerNotFound
+		}
+		return nil
+	})
+}
+
+// ExportPlayerData returns every row belonging to a player keyed by table name.
+// Values are formatted the same way as ExportRows.
+func (db *DB) ExportPlayerData(ctx context.Context, playerID int64) (map[string][]map[string]any, error) {
+	if _, err := db.GetPlayerByID(ctx, playerID); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]map[string]any, len(playerTables))
+	for _, t := range playerTables {
+		if t.others {
+			continue
+		}
+
+		// Table and column names come from playerTables above
+		rows, err := db.conn.QueryContext(ctx, `SELECT * FROM `+t.table+` WHERE `+t.column+` = ? ORDER BY rowid`, playerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.table, err)
+		}
+
+		records := []map[string]any{}
+		err = scanRows(rows, func(columns []string, values []any) error {
+			record := make(map[string]any, len(columns))
+			for i, col := range columns {
+				record[col] = values[i]
+			}
+			records = append(records, record)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", t.table, err)
+		}
+		data[t.table] = records
+	}
+
+	return data, nil
+}
+
+// DeletePlayer permanently removes a player and all of their data.
+// The deletion is recorded in the audit log with counts only.
+func (db *DB) DeletePlayer(ctx context.Context, playerID int64, actor string) (*DeleteResult, error) {
+	res := &DeleteResult{}
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := scanPlayer(tx.QueryRowContext(ctx, playerByIDQuery, playerID), &res.Player); err != nil {
+			return err
+		}
+
+		counts := make(map[string]int64, len(playerTables))
+		for _, t := range playerTables {
+			n, err := execCount(ctx, tx, `DELETE FROM `+t.table+` WHERE `+t.column+` = ?`, playerID)
+			if err != nil {
+				return fmt.Errorf("failed to delete %s: %w", t.table, err)
+			}
+			counts[t.table] += n
+		}
+		if err := deleteEmptyTeams(ctx, tx); err != nil {
+			return err
+		}
+
+		res.Scores = counts["scores"]
+		res.Keys = counts["player_keys"]
+		res.Achievements = counts["achievements"]
+		res.Renames = counts["username_history"]
+
+		if err := checkForeignKeys(ctx, tx, playerTableNames()...); err != nil {
+			return err
+		}
+
+		// The username is left out so the audit log keeps no personal data
+		if err := writeAudit(ctx, tx, actor, "delete_player", playerID, map[string]any{
+			"scores":       res.Scores,
+			"keys":         res.Keys,
+			"achievements": res.Achievements,
+			"renames":      res.Renames,
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// scanRows passes each row to fn with timestamps formatted like CURRENT_TIMESTAMP
+// and byte slices converted to strings. It closes rows.
+func scanRows(rows *sql.Rows, fn func(columns []string, values []any) error) error {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			switch v := v.(type) {
+			case time.Time:
+				values[i] = v.UTC().Format(timestampFormat)
+			case []byte:
+				values[i] = string(v)
+			}
+		}
+		if err := fn(columns, values); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeletePlayer(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	bob := newPlayer(t, db, "bob")
+	carol := newPlayer(t, db, "carol")
+
+	if err := db.SaveScore(ctx, alice.ID, 100, 8, 10, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveScore(ctx, bob.ID, 200, 16, 20, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UnlockAchievement(ctx, alice.ID, "first_game"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveGame(ctx, alice.ID, "alice's game"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := db.CreatePairingCode(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateUsername(ctx, alice.ID, "alicia"); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []struct {
+		follower *Player
+		followee string
+	}{
+		{alice, "bob"}, {bob, "alicia"}, {bob, "carol"},
+	} {
+		if _, err := db.Follow(ctx, f.follower.ID, f.followee); err != nil {
+			t.Fatalf("failed to follow %s: %v", f.followee, err)
+		}
+	}
+
+	// alice's team goes with alice, while carol and bob share another
+	solo, err := db.CreateTeam(ctx, alice.ID, "solo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := db.CreateTeam(ctx, carol.ID, "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.JoinTeam(ctx, bob.ID, "shared"); err != nil {
+		t.Fatal(err)
+	}
+
+	tournament, err := db.CreateTournament(ctx, "cup", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), 3, []int64{42}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.OpenDueTournaments(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.StartTournamentAttempt(ctx, tournament.ID, 1, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.StartTournamentAttempt(ctx, tournament.ID, 1, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, `
+		INSERT INTO tournament_results (tournament_id, rank, player_id, username, total, round_scores)
+		VALUES (?, 1, ?, 'alicia', 100, '[100]'), (?, 2, ?, 'bob', 50, '[50]')
+	`, tournament.ID, alice.ID, tournament.ID, bob.ID)
+	exec(t, db, `INSERT INTO pruned_scores (player_id, score, max_tile, games, moves, duration_seconds) VALUES (?, 50, 4, 2, 10, 30)`, alice.ID)
+	exec(t, db, `INSERT INTO pruned_days (player_id, day) VALUES (?, '2024-01-01')`, alice.ID)
+
+	res, err := db.DeletePlayer(ctx, alice.ID, "test")
+	if err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if res.Scores != 1 || res.Keys != 1 || res.Achievements != 1 || res.Renames != 1 {
+		t.Errorf("delete counted %d scores, %d keys, %d achievements and %d renames, want 1 each",
+			res.Scores, res.Keys, res.Achievements, res.Renames)
+	}
+
+	for _, pt := range playerTables {
+		if n := count(t, db, `SELECT 1 FROM `+pt.table+` WHERE `+pt.column+` = ?`, alice.ID); n != 0 {
+			t.Errorf("%s still has %d rows of the deleted player", pt.table, n)
+		}
+	}
+
+	if n := count(t, db, `SELECT 1 FROM teams WHERE id = ?`, solo.ID); n != 0 {
+		t.Error("the deleted player's emptied team still exists")
+	}
+	members, err := db.GetTeamMembers(ctx, shared.ID)
+	if err != nil || len(members) != 2 {
+		t.Errorf("shared team has %d members (%v), want 2", len(members), err)
+	}
+
+	// Other players keep their own rows
+	if n := count(t, db, `SELECT 1 FROM scores WHERE player_id = ?`, bob.ID); n != 1 {
+		t.Errorf("bob has %d scores, want 1", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM follows WHERE follower_id = ?`, bob.ID); n != 1 {
+		t.Errorf("bob follows %d players, want 1", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM tournament_attempts WHERE player_id = ?`, bob.ID); n != 1 {
+		t.Errorf("bob has %d tournament attempts, want 1", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM tournament_results WHERE player_id = ?`, bob.ID); n != 1 {
+		t.Errorf("bob has %d tournament results, want 1", n)
+	}
+
+	// Only counts are audited, never the username
+	var details string
+	if err := db.conn.QueryRowContext(ctx, `SELECT details FROM audit_log WHERE action = 'delete_player'`).Scan(&details); err != nil {
+		t.Fatalf("deletion wasn't audited: %v", err)
+	}
+	if want := `{"achievements":1,"keys":1,"renames":1,"scores":1}`; details != want {
+		t.Errorf("audit details are %s, want %s", details, want)
+	}
+
+	if _, err := db.DeletePlayer(ctx, alice.ID, "test"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("deleting again returned %v, want %v", err, ErrPlayerNotFound)
+	}
+}
+
+func TestWritesForDeletedPlayer(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+	newPlayer(t, db, "bob")
+	if _, err := db.CreateTeam(ctx, alice.ID, "sliders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DeletePlayer(ctx, alice.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A session still open for alice goes on using the old ID
+	writes := map[string]func() error{
+		"SaveScore":         func() error { return db.SaveScore(ctx, alice.ID, 100, 8, 10, time.Minute) },
+		"SaveGame":          func() error { return db.SaveGame(ctx, alice.ID, "state") },
+		"UnlockAchievement": func() error { return db.UnlockAchievement(ctx, alice.ID, "first_game") },
+		"SetHidden":         func() error { return db.SetHidden(ctx, alice.ID, true) },
+		"UpdateUsername":    func() error { return db.UpdateUsername(ctx, alice.ID, "alicia") },
+		"CreateTeam": func() error {
+			_, err := db.CreateTeam(ctx, alice.ID, "stackers")
+			return err
+		},
+		"LeaveTeam": func() error {
+			_, err := db.LeaveTeam(ctx, alice.ID)
+			return err
+		},
+		"Follow": func() error {
+			_, err := db.Follow(ctx, alice.ID, "bob")
+			return err
+		},
+		"CreatePairingCode": func() error {
+			_, _, err := db.CreatePairingCode(ctx, alice.ID)
+			return err
+		},
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrPlayerNotFound) {
+			t.Errorf("%s returned %v, want %v", name, err, ErrPlayerNotFound)
+		}
+	}
+}
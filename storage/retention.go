@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy decides which scores are old enough to prune
+type RetentionPolicy struct {
+	// MaxAge is how long every score is kept. Zero disables score pruning.
+	MaxAge time.Duration
+	// KeepTopPerPlayer scores per player are never pruned
+	KeepTopPerPlayer int
+	// KeepLeaderboard highest scores overall are never pruned
+	KeepLeaderboard int
+}
+
+// PruneReport describes what a prune removed, or would remove in a dry run
+type PruneReport struct {
+	DryRun         bool
+	Scores         int64
+	Players        int64
+	OldestScore    time.Time
+	NewestScore    time.Time
+	ExpiredPairing int64
+	ExpiredGuest   int64
+}
+
+// pruneCandidates selects scores eligible for pruning under a policy.
+// Scores in seasons that have not been archived yet are always kept.
+const pruneCandidates = `
+	SELECT id, player_id, created_at FROM (
+		SELECT
+			id,
+			player_id,
+			created_at,
+			ROW_NUMBER() OVER (PARTITION BY player_id ORDER BY score DESC, id) AS player_rank,
+			ROW_NUMBER() OVER (ORDER BY score DESC, id) AS global_rank
+		FROM scores
+	)
+	WHERE created_at < ? AND player_rank > ? AND global_rank > ?
+		AND NOT EXISTS (
+			SELECT 1 FROM seasons se
+			WHERE se.archived_at IS NULL AND created_at >= se.starts_at AND created_at < se.ends_at
+		)
+`
+
+// Prune deletes scores outside the retention policy, expired pairing codes
+// and unclaimed guest runs that have expired.
+// With dryRun set nothing is deleted and the report shows what would be.
+func (db *DB) Prune(ctx context.Context, policy RetentionPolicy, dryRun bool) (*PruneReport, error) {
+	report := &PruneReport{DryRun: dryRun}
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		if policy.MaxAge > 0 {
+			// created_at is written by CURRENT_TIMESTAMP, so compare in the same format
+			cutoff := time.Now().UTC().Add(-policy.MaxAge).Format(timestampFormat)
+			args := []any{cutoff, policy.KeepTopPerPlayer, policy.KeepLeaderboard}
+
+			var oldest, newest sql.NullString
+			if err := tx.QueryRowContext(ctx, `
+				SELECT COUNT(*), COUNT(DISTINCT player_id), MIN(created_at), MAX(created_at)
+				FROM (`+pruneCandidates+`)
+			`, args...).Scan(&report.Scores, &report.Players, &oldest, &newest); err != nil {
+				return err
+			}
+			report.OldestScore = parseTimestamp(oldest)
+			report.NewestScore = parseTimestamp(newest)
+
+			if err := foldPrunedScores(ctx, tx, args); err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				DELETE FROM scores WHERE id IN (SELECT id FROM (`+pruneCandidates+`))
+			`, args...); err != nil {
+				return err
+			}
+		}
+
+		if report.ExpiredPairing, err = execCount(ctx, tx, `
+			DELETE FROM pairing_codes WHERE expires_at <= ?
+		`, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		if report.ExpiredGuest, err = execCount(ctx, tx, `
+			DELETE FROM guest_runs WHERE expires_at <= ?
+		`, time.Now().UTC().Format(timestampFormat)); err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+	return report, nil
+}
+
+// foldPrunedScores adds the scores about to be pruned to each player's
+// pruned totals and played days, which the stats queries read alongside
+// the scores that remain
+func foldPrunedScores(ctx context.Context, tx *sql.Tx, args []any) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO pruned_scores (player_id, score, max_tile, games, moves, duration_seconds)
+		SELECT player_id, score, max_tile, COUNT(*), SUM(moves), SUM(duration_seconds)
+		FROM scores
+		WHERE id IN (SELECT id FROM (`+pruneCandidates+`))
+		GROUP BY player_id, score, max_tile
+		ON CONFLICT (player_id, score, max_tile) DO UPDATE SET
+			games = games + excluded.games,
+			moves = moves + excluded.moves,
+			duration_seconds = duration_seconds + excluded.duration_seconds
+	`, args...); err != nil {
+		return fmt.Errorf("failed to fold pruned scores: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO pruned_days (player_id, day)
+		SELECT DISTINCT player_id, date(created_at)
+		FROM scores
+		WHERE id IN (SELECT id FROM (`+pruneCandidates+`)) AND created_at IS NOT NULL
+	`, args...); err != nil {
+		return fmt.Errorf("failed to fold pruned days: %w", err)
+	}
+	return nil
+}
+
+// parseTimestamp reads a CURRENT_TIMESTAMP value, returning the zero time for NULL
+func parseTimestamp(s sql.NullString) time.Time {
+	if !s.Valid {
+		return time.Time{}
+	}
+	t, _ := time.Parse(timestampFormat, s.String)
+	return t
+}
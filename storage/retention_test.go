@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// addScore inserts a finished game played age ago
+func addScore(t *testing.T, db *DB, playerID int64, score, maxTile int, age time.Duration) {
+	t.Helper()
+	exec(t, db, `
+		INSERT INTO scores (player_id, score, max_tile, moves, duration_seconds, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, playerID, score, maxTile, score/10, score/5, time.Now().UTC().Add(-age).Format(timestampFormat))
+}
+
+// keptScores returns a player's remaining scores, best first
+func keptScores(t *testing.T, db *DB, playerID int64) []int {
+	t.Helper()
+	rows, err := db.conn.QueryContext(context.Background(), `SELECT score FROM scores WHERE player_id = ? ORDER BY score DESC`, playerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	scores := []int{}
+	for rows.Next() {
+		var s int
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		scores = append(scores, s)
+	}
+	return scores
+}
+
+func TestPrune(t *testing.T) {
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		dryRun bool
+		// season opens an unarchived season over the last ten days
+		season    bool
+		wantAlice []int
+		wantBob   []int
+		wantCount int64
+	}{
+		{
+			name:      "disabled",
+			policy:    RetentionPolicy{},
+			wantAlice: []int{400, 300, 200, 100},
+			wantBob:   []int{500, 50},
+		},
+		{
+			name:      "everything old",
+			policy:    RetentionPolicy{MaxAge: 30 * day},
+			wantAlice: []int{400},
+			wantBob:   []int{},
+			wantCount: 5,
+		},
+		{
+			name:      "keep each player's best",
+			policy:    RetentionPolicy{MaxAge: 30 * day, KeepTopPerPlayer: 1},
+			wantAlice: []int{400},
+			wantBob:   []int{500},
+			wantCount: 4,
+		},
+		{
+			name:      "keep the leaderboard",
+			policy:    RetentionPolicy{MaxAge: 30 * day, KeepLeaderboard: 3},
+			wantAlice: []int{400, 300},
+			wantBob:   []int{500},
+			wantCount: 3,
+		},
+		{
+			name:      "keep both",
+			policy:    RetentionPolicy{MaxAge: 30 * day, KeepTopPerPlayer: 2, KeepLeaderboard: 1},
+			wantAlice: []int{400, 300},
+			wantBob:   []int{500, 50},
+			wantCount: 2,
+		},
+		{
+			name:      "short retention spares the running season",
+			policy:    RetentionPolicy{MaxAge: day},
+			season:    true,
+			wantAlice: []int{400},
+			wantBob:   []int{},
+			wantCount: 5,
+		},
+		{
+			name:      "dry run",
+			policy:    RetentionPolicy{MaxAge: 30 * day},
+			dryRun:    true,
+			wantAlice: []int{400, 300, 200, 100},
+			wantBob:   []int{500, 50},
+			wantCount: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, DefaultOptions())
+			ctx := context.Background()
+			alice := newPlayer(t, db, "alice")
+			bob := newPlayer(t, db, "bob")
+
+			addScore(t, db, alice.ID, 400, 32, 2*day)
+			addScore(t, db, alice.ID, 300, 32, 40*day)
+			addScore(t, db, alice.ID, 200, 16, 50*day)
+			addScore(t, db, alice.ID, 100, 8, 60*day)
+			addScore(t, db, bob.ID, 500, 64, 45*day)
+			addScore(t, db, bob.ID, 50, 4, 45*day)
+			if tt.season {
+				if _, err := db.CreateSeason(ctx, "now", time.Now().Add(-10*day), time.Now().Add(day), "test"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			report, err := db.Prune(ctx, tt.policy, tt.dryRun)
+			if err != nil {
+				t.Fatalf("prune failed: %v", err)
+			}
+			if report.Scores != tt.wantCount {
+				t.Errorf("report counts %d scores, want %d", report.Scores, tt.wantCount)
+			}
+			if got := keptScores(t, db, alice.ID); !reflect.DeepEqual(got, tt.wantAlice) {
+				t.Errorf("alice kept %v, want %v", got, tt.wantAlice)
+			}
+			if got := keptScores(t, db, bob.ID); !reflect.DeepEqual(got, tt.wantBob) {
+				t.Errorf("bob kept %v, want %v", got, tt.wantBob)
+			}
+
+			// Every pruned game is folded into the totals, and only those
+			var folded int64
+			if err := db.conn.QueryRowContext(ctx, `SELECT COALESCE(SUM(games), 0) FROM pruned_scores`).Scan(&folded); err != nil {
+				t.Fatal(err)
+			}
+			wantFolded := tt.wantCount
+			if tt.dryRun {
+				wantFolded = 0
+			}
+			if folded != wantFolded {
+				t.Errorf("%d games folded into pruned totals, want %d", folded, wantFolded)
+			}
+		})
+	}
+}
+
+func TestPruneKeepsStats(t *testing.T) {
+	const day = 24 * time.Hour
+	db := newTestDB(t, DefaultOptions())
+	ctx := context.Background()
+	alice := newPlayer(t, db, "alice")
+
+	// Two games on some days, so folding has to merge rows and dedupe days.
+	// Games are a second apart from the start of their day to keep them on it.
+	today := time.Now().UTC().Truncate(day)
+	for i, s := range []struct {
+		score, tile int
+		daysAgo     int
+	}{
+		{100, 8, 40}, {100, 8, 40}, {250, 16, 39}, {900, 64, 38},
+		{100, 8, 37}, {300, 32, 2}, {120, 8, 1}, {80, 8, 0},
+	} {
+		played := today.Add(-time.Duration(s.daysAgo)*day + time.Duration(i)*time.Second)
+		addScore(t, db, alice.ID, s.score, s.tile, time.Since(played))
+	}
+	if err := db.UnlockAchievement(ctx, alice.ID, "first_game"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := func() *PlayerStats {
+		t.Helper()
+		st, err := db.GetPlayerStats(ctx, alice.ID, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Recent scores only ever list the scores still kept
+		st.RecentScores = nil
+		return st
+	}
+	before := stats()
+
+	report, err := db.Prune(ctx, RetentionPolicy{MaxAge: 30 * day, KeepTopPerPlayer: 1}, false)
+	if err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+	if report.Scores != 4 {
+		t.Fatalf("pruned %d scores, want 4", report.Scores)
+	}
+
+	if after := stats(); !reflect.DeepEqual(after, before) {
+		t.Errorf("stats changed after prune:\n got %+v\nwant %+v", after, before)
+	}
+	if n := count(t, db, `SELECT 1 FROM pruned_scores WHERE player_id = ?`, alice.ID); n != 2 {
+		t.Errorf("%d pruned rows, want 2", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM pruned_days WHERE player_id = ?`, alice.ID); n != 3 {
+		t.Errorf("%d pruned days, want 3", n)
+	}
+	if n := count(t, db, `SELECT 1 FROM achievements WHERE player_id = ?`, alice.ID); n != 1 {
+		t.Errorf("%d achievements left, want 1", n)
+	}
+
+	// Pruning again with nothing left to prune changes nothing
+	if _, err := db.Prune(ctx, RetentionPolicy{MaxAge: 30 * day, KeepTopPerPlayer: 1}, false); err != nil {
+		t.Fatal(err)
+	}
+	if after := stats(); !reflect.DeepEqual(after, before) {
+		t.Errorf("stats changed after a second prune:\n got %+v\nwant %+v", after, before)
+	}
+}
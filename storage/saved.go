@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoSavedGame is returned when a player has no saved game to resume
+var ErrNoSavedGame = errors.New("no saved game")
+
+// SaveGame stores a player's unfinished game so it can be resumed when they
+// next connect, replacing any game saved before. State is opaque to storage.
+func (db *DB) SaveGame(ctx context.Context, playerID int64, state string) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO saved_games (player_id, state, saved_at) VALUES (?, ?, ?)
+			ON CONFLICT (player_id) DO UPDATE SET state = excluded.state, saved_at = excluded.saved_at
+		`, playerID, state, time.Now().UTC().Format(timestampFormat))
+		if err != nil {
+			return fmt.Errorf("failed to save game: %w", err)
+		}
+		return nil
+	})
+}
+
+// TakeSavedGame returns a player's saved game and removes it, so a game is
+// only ever resumed once
+func (db *DB) TakeSavedGame(ctx context.Context, playerID int64) (string, error) {
+	var state string
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM saved_games WHERE player_id = ? RETURNING state
+		`, playerID).Scan(&state)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoSavedGame
+		}
+		return err
+	})
+	return state, err
+}
+
+// ReturnSavedGame puts back a game taken with TakeSavedGame that wasn't
+// resumed after all, unless a newer game has been saved since
+func (db *DB) ReturnSavedGame(ctx context.Context, playerID int64, state string) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO saved_games (player_id, state, saved_at) VALUES (?, ?, ?)
+			ON CONFLICT (player_id) DO NOTHING
+		`, playerID, state, time.Now().UTC().Format(timestampFormat))
+		if err != nil {
+			return fmt.Errorf("failed to return saved game: %w", err)
+		}
+		return nil
+	})
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
 	"time"
 )
 
@@ -10,22 +12,23 @@ type Score struct {
 	PlayerID  int64
 	Score     int
 	MaxTile   int
+	Moves     int
+	Duration  time.Duration
 	CreatedAt time.Time
 }
 
 // SaveScore saves a game score to the database
-func (db *DB) SaveScore(playerID int64, score, maxTile int) error {
-	_, err := db.conn.Exec(`
-		INSERT INTO scores (player_id, score, max_tile)
-		VALUES (?, ?, ?)
-	`, playerID, score, maxTile)
-	return err
+func (db *DB) SaveScore(ctx context.Context, playerID int64, score, maxTile, moves int, duration time.Duration) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.StmtContext(ctx, db.stmts.saveScore).ExecContext(ctx, playerID, score, maxTile, moves, int64(duration.Seconds()))
+		return err
+	})
 }
 
 // GetPlayerScores returns all scores for a player, ordered by score descending
-func (db *DB) GetPlayerScores(playerID int64, limit int) ([]Score, error) {
-	rows, err := db.conn.Query(`
-		SELECT id, player_id, score, max_tile, created_at
+func (db *DB) GetPlayerScores(ctx context.Context, playerID int64, limit int) ([]Score, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, player_id, score, max_tile, moves, duration_seconds, created_at
 		FROM scores
 		WHERE player_id = ?
 		ORDER BY score DESC
@@ -39,9 +42,11 @@ func (db *DB) GetPlayerScores(playerID int64, limit int) ([]Score, error) {
 	var scores []Score
 	for rows.Next() {
 		var s Score
-		if err := rows.Scan(&s.ID, &s.PlayerID, &s.Score, &s.MaxTile, &s.CreatedAt); err != nil {
+		var seconds int64
+		if err := rows.Scan(&s.ID, &s.PlayerID, &s.Score, &s.MaxTile, &s.Moves, &seconds, &s.CreatedAt); err != nil {
 			return nil, err
 		}
+		s.Duration = time.Duration(seconds) * time.Second
 		scores = append(scores, s)
 	}
 
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SeasonArchiveSize is how many leaderboard entries are frozen when a season closes
+const SeasonArchiveSize = 100
+
+var (
+	// ErrSeasonNotFound is returned when a season doesn't exist
+	ErrSeasonNotFound = errors.New("season not found")
+
+	// ErrSeasonOverlap is returned when a new season would overlap an existing one
+	ErrSeasonOverlap = errors.New("season overlaps an existing season")
+)
+
+// Season is a named period with its own leaderboard. Scores count towards
+// the season they were set in, from StartsAt up to but not including EndsAt.
+type Season struct {
+	ID         int64
+	Name       string
+	StartsAt   time.Time
+	EndsAt     time.Time
+	ArchivedAt sql.NullTime
+}
+
+// Archived reports whether the season's standings have been frozen
+func (s *Season) Archived() bool {
+	return s.ArchivedAt.Valid
+}
+
+// Active reports whether t falls within the season
+func (s *Season) Active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+const seasonColumns = `id, name, starts_at, ends_at, archived_at`
+
+func scanSeason(row interface{ Scan(...any) error }, s *Season) error {
+	err := row.Scan(&s.ID, &s.Name, &s.StartsAt, &s.EndsAt, &s.ArchivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSeasonNotFound
+	}
+	return err
+}
+
+// CreateSeason adds a season running from start until end
+func (db *DB) CreateSeason(ctx context.Context, name string, start, end time.Time, actor string) (*Season, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("season name is required")
+	}
+	if !end.After(start) {
+		return nil, errors.New("season must end after it starts")
+	}
+
+	var id int64
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		// Stored like CURRENT_TIMESTAMP so they compare correctly with scores.created_at
+		startsAt := start.UTC().Format(timestampFormat)
+		endsAt := end.UTC().Format(timestampFormat)
+
+		var overlapping int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM seasons WHERE starts_at < ? AND ends_at > ?
+		`, endsAt, startsAt).Scan(&overlapping); err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return ErrSeasonOverlap
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO seasons (name, starts_at, ends_at) VALUES (?, ?, ?)
+		`, name, startsAt, endsAt)
+		if err != nil {
+			return err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, actor, "create_season", 0, map[string]any{
+			"season_id": id,
+			"name":      name,
+			"starts_at": startsAt,
+			"ends_at":   endsAt,
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetSeason(ctx, id)
+}
+
+// GetSeason retrieves a season by ID
+func (db *DB) GetSeason(ctx context.Context, id int64) (*Season, error) {
+	season := &Season{}
+	if err := scanSeason(db.conn.QueryRowContext(ctx, `SELECT `+seasonColumns+` FROM seasons WHERE id = ?`, id), season); err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+// GetSeasonByName retrieves a season by name, ignoring case
+func (db *DB) GetSeasonByName(ctx context.Context, name string) (*Season, error) {
+	season := &Season{}
+	if err := scanSeason(db.conn.QueryRowContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons WHERE name = ? COLLATE NOCASE
+	`, strings.TrimSpace(name)), season); err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+// CurrentSeason returns the season running now, or ErrSeasonNotFound
+func (db *DB) CurrentSeason(ctx context.Context) (*Season, error) {
+	now := time.Now().UTC().Format(timestampFormat)
+	season := &Season{}
+	if err := scanSeason(db.conn.QueryRowContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons WHERE starts_at <= ? AND ends_at > ?
+	`, now, now), season); err != nil {
+		return nil, err
+	}
+	return season, nil
+}
+
+// GetSeasons returns every season that has started, newest first
+func (db *DB) GetSeasons(ctx context.Context) ([]Season, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons
+		WHERE starts_at <= ?
+		ORDER BY starts_at DESC
+	`, time.Now().UTC().Format(timestampFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []Season
+	for rows.Next() {
+		var s Season
+		if err := scanSeason(rows, &s); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, s)
+	}
+
+	return seasons, rows.Err()
+}
+
+// GetAllSeasons returns every season including upcoming ones, in start order
+func (db *DB) GetAllSeasons(ctx context.Context) ([]Season, error) {
+	rows, err := db.conn.QueryContext(ctx, `SELECT `+seasonColumns+` FROM seasons ORDER BY starts_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []Season
+	for rows.Next() {
+		var s Season
+		if err := scanSeason(rows, &s); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, s)
+	}
+
+	return seasons, rows.Err()
+}
+
+// ArchiveDueSeasons freezes the standings of every season that has ended
+// but not been archived yet, returning the seasons it archived
+func (db *DB) ArchiveDueSeasons(ctx context.Context, actor string) ([]Season, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+seasonColumns+` FROM seasons
+		WHERE archived_at IS NULL AND ends_at <= ?
+		ORDER BY starts_at
+	`, time.Now().UTC().Format(timestampFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	var due []Season
+	for rows.Next() {
+		var s Season
+		if err := scanSeason(rows, &s); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var archived []Season
+	for _, s := range due {
+		if err := db.archiveSeason(ctx, &s, actor); err != nil {
+			return archived, fmt.Errorf("failed to archive season %s: %w", s.Name, err)
+		}
+		archived = append(archived, s)
+	}
+
+	return archived, nil
+}
+
+// archiveSeason copies a season's top scores into season_standings
+func (db *DB) archiveSeason(ctx context.Context, s *Season, actor string) error {
+	now := time.Now().UTC()
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		inSeason, args := seasonFilter("s.created_at", s)
+		entries, err := execCount(ctx, tx, `
+			INSERT INTO season_standings (season_id, rank, player_id, username, score, max_tile, created_at)
+			SELECT ?, ROW_NUMBER() OVER (ORDER BY s.score DESC, s.id), p.id, p.username, s.score, s.max_tile, s.created_at
+			FROM scores s
+			JOIN players p ON s.player_id = p.id
+			WHERE p.hidden = 0 AND `+inSeason+`
+			ORDER BY s.score DESC, s.id
+			LIMIT ?
+		`, append(append([]any{s.ID}, args...), SeasonArchiveSize)...)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE seasons SET archived_at = ? WHERE id = ?`, now.Format(timestampFormat), s.ID); err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, actor, "archive_season", 0, map[string]any{
+			"season_id": s.ID,
+			"name":      s.Name,
+			"entries":   entries,
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.ArchivedAt = sql.NullTime{Time: now, Valid: true}
+	return nil
+}
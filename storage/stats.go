@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TileMilestones are the tiles tracked in player statistics
+var TileMilestones = []int{512, 1024, 2048, 4096, 8192}
+
+// TileCount records how many games reached a given tile
+type TileCount struct {
+	Tile  int
+	Games int
+}
+
+// PlayerStats holds aggregate statistics for a single player
+type PlayerStats struct {
+	GamesPlayed   int
+	AverageScore  float64
+	MedianScore   float64
+	BestScore     int
+	HighestTile   int
+	TileCounts    []TileCount
+	TotalMoves    int
+	TotalPlayTime time.Duration
+	CurrentStreak int
+	LongestStreak int
+	RecentScores  []int
+}
+
+// playerGames lists every finished game, with the pruned ones folded into
+// rows that each stand for several games
+const playerGames = `
+	SELECT player_id, score, max_tile, 1 AS games, moves, duration_seconds FROM scores
+	UNION ALL
+	SELECT player_id, score, max_tile, games, moves, duration_seconds FROM pruned_scores
+`
+
+// GetPlayerStats returns aggregate statistics for a player, including games
+// whose scores were pruned. RecentScores holds up to recentLimit of the
+// scores still kept, oldest first.
+func (db *DB) GetPlayerStats(ctx context.Context, playerID int64, recentLimit int) (*PlayerStats, error) {
+	stats := &PlayerStats{}
+
+	var totalSeconds int64
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(games), 0),
+			COALESCE(1.0 * SUM(score * games) / SUM(games), 0),
+			COALESCE(MAX(score), 0),
+			COALESCE(MAX(max_tile), 0),
+			COALESCE(SUM(moves), 0),
+			COALESCE(SUM(duration_seconds), 0)
+		FROM (`+playerGames+`)
+		WHERE player_id = ?
+	`, playerID).Scan(
+		&stats.GamesPlayed,
+		&stats.AverageScore,
+		&stats.BestScore,
+		&stats.HighestTile,
+		&stats.TotalMoves,
+		&totalSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	stats.TotalPlayTime = time.Duration(totalSeconds) * time.Second
+
+	if stats.GamesPlayed == 0 {
+		return stats, nil
+	}
+
+	if stats.MedianScore, err = db.medianScore(ctx, playerID, stats.GamesPlayed); err != nil {
+		return nil, err
+	}
+
+	if stats.TileCounts, err = db.tileCounts(ctx, playerID); err != nil {
+		return nil, err
+	}
+
+	days, err := db.playedDays(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentStreak, stats.LongestStreak = streaks(days, time.Now().UTC())
+
+	if stats.RecentScores, err = db.recentScores(ctx, playerID, recentLimit); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// medianScore returns the median of a player's scores
+func (db *DB) medianScore(ctx context.Context, playerID int64, count int) (float64, error) {
+	// The middle game, or the two middle games for an even count, counting
+	// from zero. Both are the same game for an odd count.
+	middle := []int{(count - 1) / 2, count / 2}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT score, SUM(games)
+		FROM (`+playerGames+`)
+		WHERE player_id = ?
+		GROUP BY score
+		ORDER BY score
+	`, playerID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var seen, sum, n int
+	for n < len(middle) && rows.Next() {
+		var score, games int
+		if err := rows.Scan(&score, &games); err != nil {
+			return 0, err
+		}
+		// Games seen through seen+games-1 all have this score
+		for n < len(middle) && middle[n] < seen+games {
+			sum += score
+			n++
+		}
+		seen += games
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	return float64(sum) / float64(n), nil
+}
+
+// tileCounts returns how many games reached each tile milestone.
+// A game that reached 2048 also counts as having reached 1024 and 512.
+func (db *DB) tileCounts(ctx context.Context, playerID int64) ([]TileCount, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT max_tile, SUM(games)
+		FROM (`+playerGames+`)
+		WHERE player_id = ? AND max_tile >= ?
+		GROUP BY max_tile
+	`, playerID, TileMilestones[0])
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byTile := make(map[int]int)
+	for rows.Next() {
+		var tile, games int
+		if err := rows.Scan(&tile, &games); err != nil {
+			return nil, err
+		}
+		byTile[tile] = games
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make([]TileCount, 0, len(TileMilestones))
+	for _, milestone := range TileMilestones {
+		c := TileCount{Tile: milestone}
+		for tile, games := range byTile {
+			if tile >= milestone {
+				c.Games += games
+			}
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, nil
+}
+
+// playedDays returns the distinct UTC days a player finished a game, oldest first
+func (db *DB) playedDays(ctx context.Context, playerID int64) ([]time.Time, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT date(created_at) FROM scores WHERE player_id = ?
+		UNION
+		SELECT day FROM pruned_days WHERE player_id = ?
+		ORDER BY 1
+	`, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day sql.NullString
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		if !day.Valid {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, day.String)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, t)
+	}
+
+	return days, rows.Err()
+}
+
+// streaks computes the current and longest runs of consecutive days.
+// The current streak stays alive until a full day has been missed.
+func streaks(days []time.Time, now time.Time) (current, longest int) {
+	run := 0
+	for i, day := range days {
+		if i > 0 && day.Sub(days[i-1]) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+
+	if len(days) == 0 {
+		return 0, 0
+	}
+
+	today := now.Truncate(24 * time.Hour)
+	last := days[len(days)-1]
+	if today.Sub(last) <= 24*time.Hour {
+		current = run
+	}
+
+	return current, longest
+}
+
+// recentScores returns a player's latest scores, oldest first
+func (db *DB) recentScores(ctx context.Context, playerID int64, limit int) ([]int, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT score FROM scores
+		WHERE player_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []int
+	for rows.Next() {
+		var score int
+		if err := rows.Scan(&score); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Reverse so the sparkline reads left to right in time order
+	for i, j := 0, len(scores)-1; i < j; i, j = i+1, j-1 {
+		scores[i], scores[j] = scores[j], scores[i]
+	}
+
+	return scores, nil
+}
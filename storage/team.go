@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MinTeamNameLength = 3
+	MaxTeamNameLength = 24
+)
+
+var (
+	// ErrTeamNotFound is returned when a team doesn't exist
+	ErrTeamNotFound = errors.New("team not found")
+
+	// ErrTeamNameTaken is returned when another team already uses a name
+	ErrTeamNameTaken = errors.New("that team name is already taken")
+
+	// ErrAlreadyInTeam is returned when a player in a team creates or joins another
+	ErrAlreadyInTeam = errors.New("you are already in a team, leave it first")
+
+	// ErrNotInTeam is returned when a player without a team tries to leave one
+	ErrNotInTeam = errors.New("you are not in a team")
+)
+
+// TeamNameError explains why a team name failed validation
+type TeamNameError struct {
+	Reason string
+}
+
+func (e *TeamNameError) Error() string {
+	return e.Reason
+}
+
+// TeamScoring controls how members' best scores add up to a team score
+type TeamScoring string
+
+const (
+	TeamScoringSum     TeamScoring = "sum"
+	TeamScoringAverage TeamScoring = "average"
+)
+
+// Team is a named group of players. A player belongs to at most one team,
+// and a team is deleted when its last member leaves.
+type Team struct {
+	ID        int64
+	Name      string
+	Members   int
+	CreatedAt time.Time
+}
+
+// TeamMember is a player in a team with their all-time best score
+type TeamMember struct {
+	PlayerID  int64
+	Username  string
+	Hidden    bool
+	BestScore int
+	JoinedAt  time.Time
+}
+
+// TeamStanding is a team's position on the team leaderboard.
+// Members counts the visible members with a score in the period.
+type TeamStanding struct {
+	Rank    int
+	Name    string
+	Members int
+	Score   int
+}
+
+// teamColumns lists the columns scanned by scanTeam, aliased for teams t
+const teamColumns = "t.id, t.name, (SELECT COUNT(*) FROM team_members WHERE team_id = t.id), t.created_at"
+
+func scanTeam(row *sql.Row, t *Team) error {
+	err := row.Scan(&t.ID, &t.Name, &t.Members, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTeamNotFound
+	}
+	return err
+}
+
+// ValidateTeamName checks a team name against the character rules and the
+// username blocklists. It does not check whether the name is already taken.
+func (db *DB) ValidateTeamName(name string) error {
+	n := utf8.RuneCountInString(name)
+	if n < MinTeamNameLength || n > MaxTeamNameLength {
+		return &TeamNameError{fmt.Sprintf("team names must be %d-%d characters", MinTeamNameLength, MaxTeamNameLength)}
+	}
+	if strings.TrimSpace(name) != name || strings.Contains(name, "  ") {
+		return &TeamNameError{"team names can't start or end with a space or contain double spaces"}
+	}
+
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case r == ' ' || r == '_' || r == '-' || r == '.':
+		default:
+			return &TeamNameError{"team names may only contain letters, numbers, spaces, '_', '-' and '.'"}
+		}
+	}
+
+	key := strings.ToLower(name)
+	for _, reserved := range ReservedUsernames {
+		if key == reserved {
+			return &TeamNameError{"that team name is reserved"}
+		}
+	}
+	for _, word := range db.usernames.Blocklist {
+		if word != "" && strings.Contains(key, word) {
+			return &TeamNameError{"that team name is not allowed"}
+		}
+	}
+
+	return nil
+}
+
+// CreateTeam creates a team and makes the player its first member
+func (db *DB) CreateTeam(ctx context.Context, playerID int64, name string) (*Team, error) {
+	if err := db.ValidateTeamName(name); err != nil {
+		return nil, err
+	}
+
+	team := &Team{}
+	err := db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		if err := ensureTeamless(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM teams WHERE name = ?)`, name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrTeamNameTaken
+		}
+
+		result, err := tx.ExecContext(ctx, `INSERT INTO teams (name) VALUES (?)`, name)
+		if err != nil {
+			return fmt.Errorf("failed to create team: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO team_members (player_id, team_id) VALUES (?, ?)`, playerID, id); err != nil {
+			return fmt.Errorf("failed to join team: %w", err)
+		}
+
+		if err := scanTeam(tx.QueryRowContext(ctx, `SELECT `+teamColumns+` FROM teams t WHERE t.id = ?`, id), team); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// JoinTeam adds the player to the team with the given name, ignoring case
+func (db *DB) JoinTeam(ctx context.Context, playerID int64, name string) (*Team, error) {
+	team := &Team{}
+	err := db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		if err := ensureTeamless(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		if err := scanTeam(tx.QueryRowContext(ctx, `SELECT `+teamColumns+` FROM teams t WHERE t.name = ?`, name), team); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO team_members (player_id, team_id) VALUES (?, ?)`, playerID, team.ID); err != nil {
+			return fmt.Errorf("failed to join team: %w", err)
+		}
+		team.Members++
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// LeaveTeam removes the player from their team, deleting the team if they
+// were its last member. It returns the team that was left.
+func (db *DB) LeaveTeam(ctx context.Context, playerID int64) (*Team, error) {
+	team := &Team{}
+	err := db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		err := scanTeam(tx.QueryRowContext(ctx, `
+			SELECT `+teamColumns+`
+			FROM teams t
+			JOIN team_members tm ON tm.team_id = t.id
+			WHERE tm.player_id = ?
+		`, playerID), team)
+		if errors.Is(err, ErrTeamNotFound) {
+			return ErrNotInTeam
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM team_members WHERE player_id = ?`, playerID); err != nil {
+			return fmt.Errorf("failed to leave team: %w", err)
+		}
+		team.Members--
+
+		if err := deleteEmptyTeams(ctx, tx); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// GetPlayerTeam returns the team a player belongs to, or ErrNotInTeam
+func (db *DB) GetPlayerTeam(ctx context.Context, playerID int64) (*Team, error) {
+	team := &Team{}
+	err := scanTeam(db.conn.QueryRowContext(ctx, `
+		SELECT `+teamColumns+`
+		FROM teams t
+		JOIN team_members tm ON tm.team_id = t.id
+		WHERE tm.player_id = ?
+	`, playerID), team)
+	if errors.Is(err, ErrTeamNotFound) {
+		return nil, ErrNotInTeam
+	}
+	if err != nil {
+		return nil, err
+	}
+	return team, nil
+}
+
+// GetTeams returns every team, largest first
+func (db *DB) GetTeams(ctx context.Context) ([]Team, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT t.id, t.name, COUNT(tm.player_id), t.created_at
+		FROM teams t
+		LEFT JOIN team_members tm ON tm.team_id = t.id
+		GROUP BY t.id
+		ORDER BY COUNT(tm.player_id) DESC, t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.ID, &t.Name, &t.Members, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+
+	return teams, rows.Err()
+}
+
+// GetTeamMembers returns a team's members, best score first.
+// BestScore is zero for members who hide from leaderboards.
+func (db *DB) GetTeamMembers(ctx context.Context, teamID int64) ([]TeamMember, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT
+			p.id,
+			p.username,
+			p.hidden,
+			CASE WHEN p.hidden THEN 0 ELSE COALESCE(MAX(s.score), 0) END AS best,
+			tm.joined_at
+		FROM team_members tm
+		JOIN players p ON p.id = tm.player_id
+		LEFT JOIN scores s ON s.player_id = p.id
+		WHERE tm.team_id = ?
+		GROUP BY p.id
+		ORDER BY best DESC, p.username_key
+	`, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []TeamMember
+	for rows.Next() {
+		var m TeamMember
+		if err := rows.Scan(&m.PlayerID, &m.Username, &m.Hidden, &m.BestScore, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+// GetTeamLeaderboard ranks teams by their current visible members' best
+// scores in a season, or of all time when season is nil
+func (db *DB) GetTeamLeaderboard(ctx context.Context, season *Season, scoring TeamScoring, limit int) ([]TeamStanding, error) {
+	var aggregate string
+	switch scoring {
+	case TeamScoringSum:
+		aggregate = "SUM(b.score)"
+	case TeamScoringAverage:
+		aggregate = "CAST(ROUND(AVG(b.score)) AS INTEGER)"
+	default:
+		return nil, fmt.Errorf("unknown team scoring %q", scoring)
+	}
+
+	source, args := seasonScores(season)
+	args = append(args, limit)
+
+	rows, err := db.conn.QueryContext(ctx, `
+		WITH best AS (
+			SELECT s.player_id, MAX(s.score) AS score
+			FROM (`+source+`) s
+			JOIN players p ON p.id = s.player_id
+			WHERE p.hidden = 0
+			GROUP BY s.player_id
+		)
+		SELECT t.name, COUNT(*), `+aggregate+` AS total
+		FROM teams t
+		JOIN team_members tm ON tm.team_id = t.id
+		JOIN best b ON b.player_id = tm.player_id
+		GROUP BY t.id
+		ORDER BY total DESC, t.name
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var standings []TeamStanding
+	rank := 1
+	for rows.Next() {
+		var s TeamStanding
+		if err := rows.Scan(&s.Name, &s.Members, &s.Score); err != nil {
+			return nil, err
+		}
+		s.Rank = rank
+		rank++
+		standings = append(standings, s)
+	}
+
+	return standings, rows.Err()
+}
+
+// ensureTeamless fails with ErrAlreadyInTeam if the player belongs to a team
+func ensureTeamless(ctx context.Context, tx *sql.Tx, playerID int64) error {
+	var inTeam bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM team_members WHERE player_id = ?)`, playerID).Scan(&inTeam); err != nil {
+		return err
+	}
+	if inTeam {
+		return ErrAlreadyInTeam
+	}
+	return nil
+}
+
+// deleteEmptyTeams removes teams that no longer have any members
+func deleteEmptyTeams(ctx context.Context, e execer) error {
+	if _, err := e.ExecContext(ctx, `DELETE FROM teams WHERE id NOT IN (SELECT team_id FROM team_members)`); err != nil {
+		return fmt.Errorf("failed to delete empty teams: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,610 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// TournamentResultsSize is how many standings are frozen when a tournament is finalized
+	TournamentResultsSize = 100
+
+	// TournamentGrace is how long after a tournament ends attempts already
+	// under way may still be finished before the results are frozen
+	TournamentGrace = 5 * time.Minute
+
+	// MaxTournamentRounds caps the number of rounds in a tournament
+	MaxTournamentRounds = 10
+)
+
+var (
+	// ErrTournamentNotFound is returned when a tournament doesn't exist
+	ErrTournamentNotFound = errors.New("tournament not found")
+
+	// ErrTournamentNotRunning is returned when starting an attempt outside a tournament's window
+	ErrTournamentNotRunning = errors.New("tournament is not running")
+
+	// ErrNoAttemptsLeft is returned once a player has used every attempt at a round
+	ErrNoAttemptsLeft = errors.New("no attempts left for this round")
+
+	// ErrAttemptClosed is returned when finishing an attempt that is already
+	// finished or whose tournament has been finalized
+	ErrAttemptClosed = errors.New("this attempt can no longer be scored")
+)
+
+// Tournament is a timed competition of seeded rounds. Every player gets the
+// same tiles in a round and a fixed number of attempts at it; their total is
+// the sum of their best score in each round.
+type Tournament struct {
+	ID          int64
+	Name        string
+	StartsAt    time.Time
+	EndsAt      time.Time
+	Attempts    int
+	Seeds       []int64
+	OpenedAt    sql.NullTime
+	FinalizedAt sql.NullTime
+}
+
+// Rounds returns the number of rounds
+func (t *Tournament) Rounds() int {
+	return len(t.Seeds)
+}
+
+// Finalized reports whether the results have been frozen
+func (t *Tournament) Finalized() bool {
+	return t.FinalizedAt.Valid
+}
+
+// Running reports whether attempts can be started at t
+func (t *Tournament) Running(now time.Time) bool {
+	return t.OpenedAt.Valid && !t.Finalized() && now.Before(t.EndsAt)
+}
+
+// Status describes where the tournament is in its schedule
+func (t *Tournament) Status(now time.Time) string {
+	switch {
+	case t.Finalized():
+		return "finalized"
+	case !now.Before(t.EndsAt):
+		return "closing"
+	case t.OpenedAt.Valid:
+		return "running"
+	case !now.Before(t.StartsAt):
+		return "opening"
+	}
+	return "upcoming"
+}
+
+// TournamentAttempt is one play of a tournament round
+type TournamentAttempt struct {
+	ID           int64
+	TournamentID int64
+	Round        int
+	Seed         int64
+	Number       int
+}
+
+// RoundProgress summarizes a player's attempts at one round
+type RoundProgress struct {
+	Round int
+	Used  int
+	Best  int
+}
+
+// TournamentStanding is a player's position in a tournament.
+// RoundScores holds their best score in each round, in round order.
+type TournamentStanding struct {
+	Rank        int
+	PlayerID    int64
+	Username    string
+	Total       int
+	RoundScores []int
+}
+
+const tournamentColumns = `id, name, starts_at, ends_at, attempts, opened_at, finalized_at`
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func scanTournament(row interface{ Scan(...any) error }, t *Tournament) error {
+	err := row.Scan(&t.ID, &t.Name, &t.StartsAt, &t.EndsAt, &t.Attempts, &t.OpenedAt, &t.FinalizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTournamentNotFound
+	}
+	return err
+}
+
+// loadSeeds fills in a tournament's round seeds
+func loadSeeds(ctx context.Context, q querier, t *Tournament) error {
+	rows, err := q.QueryContext(ctx, `SELECT seed FROM tournament_rounds WHERE tournament_id = ? ORDER BY round`, t.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	t.Seeds = nil
+	for rows.Next() {
+		var seed int64
+		if err := rows.Scan(&seed); err != nil {
+			return err
+		}
+		t.Seeds = append(t.Seeds, seed)
+	}
+	return rows.Err()
+}
+
+// queryTournaments returns the tournaments matched by a query selecting tournamentColumns
+func (db *DB) queryTournaments(ctx context.Context, query string, args ...any) ([]Tournament, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var tournaments []Tournament
+	for rows.Next() {
+		var t Tournament
+		if err := scanTournament(rows, &t); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range tournaments {
+		if err := loadSeeds(ctx, db.conn, &tournaments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tournaments, nil
+}
+
+// CreateTournament schedules a tournament with one round per seed.
+// Seeds must be non-zero since a zero seed means an unseeded game.
+func (db *DB) CreateTournament(ctx context.Context, name string, start, end time.Time, attempts int, seeds []int64, actor string) (*Tournament, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return nil, errors.New("tournament name is required")
+	case !end.After(start):
+		return nil, errors.New("tournament must end after it starts")
+	case attempts < 1:
+		return nil, errors.New("tournament needs at least one attempt per round")
+	case len(seeds) < 1 || len(seeds) > MaxTournamentRounds:
+		return nil, fmt.Errorf("tournaments have 1-%d rounds", MaxTournamentRounds)
+	}
+	for _, seed := range seeds {
+		if seed == 0 {
+			return nil, errors.New("round seeds must be non-zero")
+		}
+	}
+
+	var id int64
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tournaments WHERE name = ?)`, name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("a tournament named %q already exists", name)
+		}
+
+		// Stored like CURRENT_TIMESTAMP so they compare correctly with other timestamps
+		startsAt := start.UTC().Format(timestampFormat)
+		endsAt := end.UTC().Format(timestampFormat)
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO tournaments (name, starts_at, ends_at, attempts) VALUES (?, ?, ?, ?)
+		`, name, startsAt, endsAt, attempts)
+		if err != nil {
+			return fmt.Errorf("failed to create tournament: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		for i, seed := range seeds {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO tournament_rounds (tournament_id, round, seed) VALUES (?, ?, ?)
+			`, id, i+1, seed); err != nil {
+				return fmt.Errorf("failed to create round %d: %w", i+1, err)
+			}
+		}
+
+		if err := writeAudit(ctx, tx, actor, "create_tournament", 0, map[string]any{
+			"tournament_id": id,
+			"name":          name,
+			"starts_at":     startsAt,
+			"ends_at":       endsAt,
+			"attempts":      attempts,
+			"rounds":        len(seeds),
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return db.GetTournament(ctx, id)
+}
+
+// GetTournament retrieves a tournament by ID
+func (db *DB) GetTournament(ctx context.Context, id int64) (*Tournament, error) {
+	t := &Tournament{}
+	if err := scanTournament(db.conn.QueryRowContext(ctx, `SELECT `+tournamentColumns+` FROM tournaments WHERE id = ?`, id), t); err != nil {
+		return nil, err
+	}
+	return t, loadSeeds(ctx, db.conn, t)
+}
+
+// GetTournamentByName retrieves a tournament by name, ignoring case
+func (db *DB) GetTournamentByName(ctx context.Context, name string) (*Tournament, error) {
+	t := &Tournament{}
+	if err := scanTournament(db.conn.QueryRowContext(ctx, `
+		SELECT `+tournamentColumns+` FROM tournaments WHERE name = ?
+	`, strings.TrimSpace(name)), t); err != nil {
+		return nil, err
+	}
+	return t, loadSeeds(ctx, db.conn, t)
+}
+
+// GetTournaments returns every tournament, latest start first
+func (db *DB) GetTournaments(ctx context.Context) ([]Tournament, error) {
+	return db.queryTournaments(ctx, `SELECT `+tournamentColumns+` FROM tournaments ORDER BY starts_at DESC, id DESC`)
+}
+
+// OpenDueTournaments opens every tournament whose start time has passed,
+// returning the tournaments it opened
+func (db *DB) OpenDueTournaments(ctx context.Context) ([]Tournament, error) {
+	now := time.Now().UTC().Format(timestampFormat)
+	due, err := db.queryTournaments(ctx, `
+		SELECT `+tournamentColumns+` FROM tournaments
+		WHERE opened_at IS NULL AND starts_at <= ? AND ends_at > ?
+		ORDER BY starts_at
+	`, now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, t := range due {
+			if _, err := tx.ExecContext(ctx, `UPDATE tournaments SET opened_at = ? WHERE id = ?`, now, t.ID); err != nil {
+				return fmt.Errorf("failed to open tournament %s: %w", t.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range due {
+		due[i].OpenedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	return due, nil
+}
+
+// FinalizeDueTournaments freezes the results of every tournament whose end
+// and grace period have passed, returning the tournaments it finalized
+func (db *DB) FinalizeDueTournaments(ctx context.Context, actor string) ([]Tournament, error) {
+	due, err := db.queryTournaments(ctx, `
+		SELECT `+tournamentColumns+` FROM tournaments
+		WHERE finalized_at IS NULL AND ends_at <= ?
+		ORDER BY ends_at
+	`, time.Now().Add(-TournamentGrace).UTC().Format(timestampFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	var finalized []Tournament
+	for _, t := range due {
+		if err := db.finalizeTournament(ctx, &t, actor); err != nil {
+			return finalized, fmt.Errorf("failed to finalize tournament %s: %w", t.Name, err)
+		}
+		finalized = append(finalized, t)
+	}
+	return finalized, nil
+}
+
+// finalizeTournament copies a tournament's standings into tournament_results
+func (db *DB) finalizeTournament(ctx context.Context, t *Tournament, actor string) error {
+	now := time.Now().UTC()
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		standings, err := liveStandings(ctx, tx, t)
+		if err != nil {
+			return err
+		}
+		if len(standings) > TournamentResultsSize {
+			standings = standings[:TournamentResultsSize]
+		}
+
+		for _, s := range standings {
+			rounds, err := json.Marshal(s.RoundScores)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO tournament_results (tournament_id, rank, player_id, username, total, round_scores)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, t.ID, s.Rank, s.PlayerID, s.Username, s.Total, string(rounds)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE tournaments SET finalized_at = ? WHERE id = ?`, now.Format(timestampFormat), t.ID); err != nil {
+			return err
+		}
+
+		if err := writeAudit(ctx, tx, actor, "finalize_tournament", 0, map[string]any{
+			"tournament_id": t.ID,
+			"name":          t.Name,
+			"entries":       len(standings),
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	t.FinalizedAt = sql.NullTime{Time: now, Valid: true}
+	return nil
+}
+
+// NextTournamentEvent returns when a tournament next needs opening or
+// finalizing. ok is false when nothing is scheduled.
+func (db *DB) NextTournamentEvent(ctx context.Context) (next time.Time, ok bool, err error) {
+	var at sql.NullString
+	err = db.conn.QueryRowContext(ctx, `
+		SELECT MIN(at) FROM (
+			SELECT starts_at AS at FROM tournaments WHERE opened_at IS NULL AND ends_at > ?
+			UNION ALL
+			SELECT datetime(ends_at, ?) FROM tournaments WHERE finalized_at IS NULL
+		)
+	`, time.Now().UTC().Format(timestampFormat), fmt.Sprintf("+%d seconds", int(TournamentGrace.Seconds()))).Scan(&at)
+	if err != nil || !at.Valid {
+		return time.Time{}, false, err
+	}
+
+	next, err = time.Parse(timestampFormat, at.String)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return next, true, nil
+}
+
+// StartTournamentAttempt uses up one of the player's attempts at a round and
+// returns the seed to play it with
+func (db *DB) StartTournamentAttempt(ctx context.Context, tournamentID int64, round int, playerID int64) (*TournamentAttempt, error) {
+	attempt := &TournamentAttempt{TournamentID: tournamentID, Round: round}
+	err := db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		t := &Tournament{}
+		if err := scanTournament(tx.QueryRowContext(ctx, `SELECT `+tournamentColumns+` FROM tournaments WHERE id = ?`, tournamentID), t); err != nil {
+			return err
+		}
+		if !t.Running(time.Now()) {
+			return ErrTournamentNotRunning
+		}
+
+		err := tx.QueryRowContext(ctx, `
+			SELECT seed FROM tournament_rounds WHERE tournament_id = ? AND round = ?
+		`, tournamentID, round).Scan(&attempt.Seed)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("tournament %s has no round %d", t.Name, round)
+		}
+		if err != nil {
+			return err
+		}
+
+		var used int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM tournament_attempts
+			WHERE tournament_id = ? AND player_id = ? AND round = ?
+		`, tournamentID, playerID, round).Scan(&used); err != nil {
+			return err
+		}
+		if used >= t.Attempts {
+			return ErrNoAttemptsLeft
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO tournament_attempts (tournament_id, round, player_id) VALUES (?, ?, ?)
+		`, tournamentID, round, playerID)
+		if err != nil {
+			return fmt.Errorf("failed to start attempt: %w", err)
+		}
+		attempt.ID, err = result.LastInsertId()
+		attempt.Number = used + 1
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// FinishTournamentAttempt records the final score of an attempt. Each attempt
+// can be finished once, and only until its tournament is finalized.
+func (db *DB) FinishTournamentAttempt(ctx context.Context, attemptID, playerID int64, score, maxTile, moves int) error {
+	return db.writePlayer(ctx, playerID, func(ctx context.Context, tx *sql.Tx) error {
+		n, err := execCount(ctx, tx, `
+			UPDATE tournament_attempts
+			SET score = ?, max_tile = ?, moves = ?, finished_at = ?
+			WHERE id = ? AND player_id = ? AND finished_at IS NULL
+				AND tournament_id IN (SELECT id FROM tournaments WHERE finalized_at IS NULL)
+		`, score, maxTile, moves, time.Now().UTC().Format(timestampFormat), attemptID, playerID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrAttemptClosed
+		}
+		return nil
+	})
+}
+
+// GetRoundProgress returns how many attempts a player has used at each round
+// of a tournament and their best finished score, in round order
+func (db *DB) GetRoundProgress(ctx context.Context, t *Tournament, playerID int64) ([]RoundProgress, error) {
+	progress := make([]RoundProgress, t.Rounds())
+	for i := range progress {
+		progress[i].Round = i + 1
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT round, COUNT(*), COALESCE(MAX(CASE WHEN finished_at IS NOT NULL THEN score END), 0)
+		FROM tournament_attempts
+		WHERE tournament_id = ? AND player_id = ?
+		GROUP BY round
+	`, t.ID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var round, used, best int
+		if err := rows.Scan(&round, &used, &best); err != nil {
+			return nil, err
+		}
+		if round >= 1 && round <= len(progress) {
+			progress[round-1].Used = used
+			progress[round-1].Best = best
+		}
+	}
+
+	return progress, rows.Err()
+}
+
+// GetTournamentStandings returns a tournament's standings among visible
+// players. They are live while it runs and frozen once it is finalized.
+func (db *DB) GetTournamentStandings(ctx context.Context, t *Tournament, limit int) ([]TournamentStanding, error) {
+	if !t.Finalized() {
+		standings, err := liveStandings(ctx, db.conn, t)
+		if len(standings) > limit {
+			standings = standings[:limit]
+		}
+		return standings, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT r.rank, r.player_id, r.username, r.total, r.round_scores
+		FROM tournament_results r
+		JOIN players p ON p.id = r.player_id
+		WHERE r.tournament_id = ? AND p.hidden = 0
+		ORDER BY r.rank
+		LIMIT ?
+	`, t.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var standings []TournamentStanding
+	for rows.Next() {
+		var s TournamentStanding
+		var rounds string
+		if err := rows.Scan(&s.Rank, &s.PlayerID, &s.Username, &s.Total, &rounds); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(rounds), &s.RoundScores); err != nil {
+			return nil, fmt.Errorf("invalid round scores for rank %d: %w", s.Rank, err)
+		}
+		standings = append(standings, s)
+	}
+
+	return standings, rows.Err()
+}
+
+// liveStandings ranks visible players by the sum of their best finished
+// score in each round. Ties go to whoever reached their total first.
+func liveStandings(ctx context.Context, q querier, t *Tournament) ([]TournamentStanding, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT a.player_id, p.username, a.round, a.score, a.finished_at
+		FROM (
+			SELECT player_id, round, score, finished_at,
+				ROW_NUMBER() OVER (PARTITION BY player_id, round ORDER BY score DESC, finished_at) AS n
+			FROM tournament_attempts
+			WHERE tournament_id = ? AND finished_at IS NOT NULL
+		) a
+		JOIN players p ON p.id = a.player_id
+		WHERE a.n = 1 AND p.hidden = 0
+	`, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type entry struct {
+		standing  TournamentStanding
+		reachedAt time.Time
+	}
+	byPlayer := make(map[int64]*entry)
+	for rows.Next() {
+		var playerID int64
+		var username string
+		var round, score int
+		var finishedAt time.Time
+		if err := rows.Scan(&playerID, &username, &round, &score, &finishedAt); err != nil {
+			return nil, err
+		}
+		if round < 1 || round > t.Rounds() {
+			continue
+		}
+
+		e, ok := byPlayer[playerID]
+		if !ok {
+			e = &entry{standing: TournamentStanding{
+				PlayerID:    playerID,
+				Username:    username,
+				RoundScores: make([]int, t.Rounds()),
+			}}
+			byPlayer[playerID] = e
+		}
+		e.standing.RoundScores[round-1] = score
+		e.standing.Total += score
+		if finishedAt.After(e.reachedAt) {
+			e.reachedAt = finishedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*entry, 0, len(byPlayer))
+	for _, e := range byPlayer {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.standing.Total != b.standing.Total {
+			return a.standing.Total > b.standing.Total
+		}
+		if !a.reachedAt.Equal(b.reachedAt) {
+			return a.reachedAt.Before(b.reachedAt)
+		}
+		return a.standing.PlayerID < b.standing.PlayerID
+	})
+
+	standings := make([]TournamentStanding, len(entries))
+	for i, e := range entries {
+		standings[i] = e.standing
+		standings[i].Rank = i + 1
+	}
+	return standings, nil
+}
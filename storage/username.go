@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 20
+)
+
+// ReservedUsernames can never be registered, regardless of configuration
+var ReservedUsernames = []string{
+	"admin", "administrator", "anonymous", "guest", "mod", "moderator",
+	"root", "server", "support", "system",
+}
+
+var (
+	// ErrUsernameTaken is returned when another player already uses a username
+	ErrUsernameTaken = errors.New("that username is already taken")
+
+	// ErrRenameCooldown is returned when a player renames too soon after the last rename
+	ErrRenameCooldown = errors.New("you renamed recently, please wait before renaming again")
+)
+
+// UsernameError explains why a username failed validation
+type UsernameError struct {
+	Reason string
+}
+
+func (e *UsernameError) Error() string {
+	return e.Reason
+}
+
+// UsernamePolicy controls which usernames are accepted and how often they may change
+type UsernamePolicy struct {
+	// Blocklist entries are rejected if they appear anywhere in a username
+	Blocklist      []string
+	RenameCooldown time.Duration
+}
+
+// UsernameChange is a single entry in a player's rename history
+type UsernameChange struct {
+	OldUsername string
+	NewUsername string
+	ChangedAt   time.Time
+}
+
+// SetUsernamePolicy replaces the policy used when creating and renaming players
+func (db *DB) SetUsernamePolicy(policy UsernamePolicy) {
+	for i, word := range policy.Blocklist {
+		policy.Blocklist[i] = strings.ToLower(strings.TrimSpace(word))
+	}
+	db.usernames = policy
+}
+
+// ValidateUsername checks a username against the character rules and blocklists.
+// It does not check whether the name is already taken.
+func (db *DB) ValidateUsername(username string) error {
+	n := utf8.RuneCountInString(username)
+	if n < MinUsernameLength || n > MaxUsernameLength {
+		return &UsernameError{fmt.Sprintf("usernames must be %d-%d characters", MinUsernameLength, MaxUsernameLength)}
+	}
+
+	for i, r := range username {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case i > 0 && (r == '_' || r == '-' || r == '.'):
+		default:
+			return &UsernameError{"usernames may only contain letters, numbers, '_', '-' and '.', and must start with a letter or number"}
+		}
+	}
+
+	key := usernameKey(username)
+	for _, reserved := range ReservedUsernames {
+		if key == reserved {
+			return &UsernameError{"that username is reserved"}
+		}
+	}
+	for _, word := range db.usernames.Blocklist {
+		if word != "" && strings.Contains(key, word) {
+			return &UsernameError{"that username is not allowed"}
+		}
+	}
+
+	return nil
+}
+
+// GetUsernameHistory returns a player's renames, most recent first
+func (db *DB) GetUsernameHistory(ctx context.Context, playerID int64, limit int) ([]UsernameChange, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT old_username, new_username, changed_at
+		FROM username_history
+		WHERE player_id = ?
+		ORDER BY changed_at DESC, id DESC
+		LIMIT ?
+	`, playerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []UsernameChange
+	for rows.Next() {
+		var c UsernameChange
+		if err := rows.Scan(&c.OldUsername, &c.NewUsername, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// NextRenameAt returns when a player is next allowed to rename.
+// A zero time means the player may rename now.
+func (db *DB) NextRenameAt(ctx context.Context, playerID int64) (time.Time, error) {
+	return db.nextRenameAt(ctx, db.conn, playerID)
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (db *DB) nextRenameAt(ctx context.Context, q rowQuerier, playerID int64) (time.Time, error) {
+	if db.usernames.RenameCooldown <= 0 {
+		return time.Time{}, nil
+	}
+
+	var last time.Time
+	err := q.QueryRowContext(ctx, `
+		SELECT changed_at FROM username_history
+		WHERE player_id = ?
+		ORDER BY changed_at DESC
+		LIMIT 1
+	`, playerID).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := last.Add(db.usernames.RenameCooldown)
+	if time.Now().After(next) {
+		return time.Time{}, nil
+	}
+	return next, nil
+}
+
+// usernameKey is the case-folded form used to enforce uniqueness
+func usernameKey(username string) string {
+	return strings.ToLower(username)
+}
+
+// isUsernameConflict reports whether err is a unique violation on username_key
+func isUsernameConflict(err error) bool {
+	var coded interface{ Code() int }
+	if !errors.As(err, &coded) || coded.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return false
+	}
+	return strings.Contains(err.Error(), "username_key")
+}
+
+// dedupeUsernames backfills username_key, renaming later players whose
+// names collide case-insensitively, then enforces uniqueness
+func dedupeUsernames(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, username FROM players ORDER BY id`)
+	if err != nil {
+		return err
+	}
+
+	type player struct {
+		id       int64
+		username string
+	}
+	var players []player
+	for rows.Next() {
+		var p player
+		if err := rows.Scan(&p.id, &p.username); err != nil {
+			rows.Close()
+			return err
+		}
+		players = append(players, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(players))
+	for _, p := range players {
+		name := p.username
+		if seen[usernameKey(name)] {
+			// An earlier player may already have the suffixed name
+			name = fmt.Sprintf("%s-%d", p.username, p.id)
+			for n := 2; seen[usernameKey(name)]; n++ {
+				name = fmt.Sprintf("%s-%d-%d", p.username, p.id, n)
+			}
+			// Backdate the entry so the forced rename doesn't start a cooldown
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO username_history (player_id, old_username, new_username, changed_at)
+				SELECT id, ?, ?, created_at FROM players WHERE id = ?
+			`, p.username, name, p.id); err != nil {
+				return err
+			}
+		}
+		seen[usernameKey(name)] = true
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE players SET username = ?, username_key = ? WHERE id = ?
+		`, name, usernameKey(name), p.id); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS idx_players_username_key ON players(username_key)`)
+	return err
+}
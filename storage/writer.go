@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// maxWriteBatch caps how many queued writes share one transaction
+const maxWriteBatch = 64
+
+// errRollback is returned by a write's fn to roll it back without failing,
+// as dry runs do
+var errRollback = errors.New("rolled back")
+
+// writeRequest is a write waiting for the single writer
+type writeRequest struct {
+	ctx    context.Context
+	fn     func(ctx context.Context, tx *sql.Tx) error
+	record bool
+	done   chan error
+}
+
+// write runs fn in a write transaction. With the queue enabled, writes from
+// every session are funnelled through one goroutine and committed in batches,
+// so concurrent sessions never compete for SQLite's write lock.
+func (db *DB) write(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return db.runWrite(ctx, fn, true)
+}
+
+// runWrite runs fn like write, noting the outcome in writeHealth when record is set
+func (db *DB) runWrite(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error, record bool) error {
+	if db.writes == nil {
+		var fnErr error
+		err := func() error {
+			tx, err := db.conn.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			if fnErr = fn(ctx, tx); fnErr != nil {
+				return fnErr
+			}
+			return tx.Commit()
+		}()
+		// A write refused by its own checks says nothing about the database
+		if record && (fnErr == nil || isWriteFault(fnErr)) {
+			db.writeHealth.record(err)
+		}
+		return err
+	}
+
+	// A write abandoned here may still reach the writer, which skips it
+	req := writeRequest{ctx: ctx, fn: fn, record: record, done: make(chan error, 1)}
+	select {
+	case db.writes <- req:
+	case <-db.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-db.writerDone:
+		// The writer may have finished this request just before exiting
+		select {
+		case err := <-req.done:
+			return err
+		default:
+			return ErrClosed
+		}
+	}
+}
+
+// runWriter commits queued writes until the database is closed, then
+// finishes whatever is still queued
+func (db *DB) runWriter() {
+	defer close(db.writerDone)
+
+	for {
+		select {
+		case req := <-db.writes:
+			db.commitBatch(db.collectBatch(req))
+		case <-db.closing:
+			for {
+				select {
+				case req := <-db.writes:
+					db.commitBatch(db.collectBatch(req))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collectBatch takes first plus any writes already waiting, up to maxWriteBatch
+func (db *DB) collectBatch(first writeRequest) []writeRequest {
+	batch := []writeRequest{first}
+	for len(batch) < maxWriteBatch {
+		select {
+		case req := <-db.writes:
+			batch = append(batch, req)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// commitBatch runs a batch in one transaction. Each write gets a savepoint
+// so one failing write is rolled back without undoing the others.
+func (db *DB) commitBatch(batch []writeRequest) {
+	errs := make([]error, len(batch))
+
+	err := func() error {
+		// The batch outlives any one caller; each write still runs with its caller's context
+		ctx := context.Background()
+		tx, err := db.writer.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		for i, req := range batch {
+			if errs[i] = req.ctx.Err(); errs[i] != nil {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT queued_write`); err != nil {
+				return err
+			}
+			// Cancelling a statement interrupts the whole transaction, so
+			// writes that have started run to completion
+			if errs[i] = req.fn(context.WithoutCancel(req.ctx), tx); errs[i] != nil {
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO queued_write`); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `RELEASE queued_write`); err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit()
+	}()
+	// A failed batch fails every write in it, otherwise a write failing in the
+	// database marks the batch unhealthy. Probes from CheckWrite don't count.
+	recorded := false
+	outcome := err
+	for i, req := range batch {
+		if !req.record {
+			continue
+		}
+		recorded = true
+		if outcome == nil && isWriteFault(errs[i]) {
+			outcome = errs[i]
+		}
+	}
+	if recorded {
+		db.writeHealth.record(outcome)
+	}
+
+	for i, req := range batch {
+		if err != nil {
+			req.done <- err
+		} else {
+			req.done <- errs[i]
+		}
+	}
+}
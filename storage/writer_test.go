@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestDB opens a migrated database in a temporary directory with a
+// scratch table t for the tests to write to
+func newTestDB(t *testing.T, opts Options) *DB {
+	t.Helper()
+	ctx := context.Background()
+	db, err := NewDB(ctx, filepath.Join(t.TempDir(), "test.db"), opts)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.conn.ExecContext(ctx, `CREATE TABLE t (v INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	return db
+}
+
+// insert returns a write that adds v to t
+func insert(v int) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO t (v) VALUES (?)`, v)
+		return err
+	}
+}
+
+// values returns the contents of t in order
+func values(t *testing.T, db *DB) []int {
+	t.Helper()
+	rows, err := db.conn.QueryContext(context.Background(), `SELECT v FROM t ORDER BY v`)
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	defer rows.Close()
+
+	var vs []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("failed to scan: %v", err)
+		}
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// holdWriter occupies the writer until release is called, so the writes
+// queued meanwhile are committed together in one batch
+func holdWriter(t *testing.T, db *DB) (release func()) {
+	t.Helper()
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- db.write(context.Background(), func(context.Context, *sql.Tx) error {
+			close(started)
+			<-unblock
+			return nil
+		})
+	}()
+	<-started
+
+	return func() {
+		close(unblock)
+		if err := <-done; err != nil {
+			t.Errorf("holding write failed: %v", err)
+		}
+	}
+}
+
+// queueWrites starts one write per fn and waits until all are queued. The
+// returned function waits for them and returns their errors in order.
+func queueWrites(t *testing.T, db *DB, ctxs []context.Context, fns ...func(ctx context.Context, tx *sql.Tx) error) func() []error {
+	t.Helper()
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		ctx := context.Background()
+		if ctxs != nil {
+			ctx = ctxs[i]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = db.write(ctx, fn)
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(db.writes) < len(fns) {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d writes queued", len(db.writes), len(fns))
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return func() []error {
+		wg.Wait()
+		return errs
+	}
+}
+
+func TestWriteRollsBackOnlyTheFailedWrite(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+
+	failure := errors.New("write failed")
+	release := holdWriter(t, db)
+	wait := queueWrites(t, db, nil,
+		insert(1),
+		func(ctx context.Context, tx *sql.Tx) error {
+			if err := insert(2)(ctx, tx); err != nil {
+				return err
+			}
+			return failure
+		},
+		insert(3),
+	)
+	release()
+	errs := wait()
+
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("writes around the failed one returned %v and %v, want nil", errs[0], errs[2])
+	}
+	if !errors.Is(errs[1], failure) {
+		t.Errorf("failed write returned %v, want %v", errs[1], failure)
+	}
+	if got := values(t, db); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("table holds %v, want [1 3]", got)
+	}
+}
+
+func TestWriteSkipsCancelledWrites(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran bool
+	release := holdWriter(t, db)
+	wait := queueWrites(t, db, []context.Context{ctx, context.Background()},
+		func(ctx context.Context, tx *sql.Tx) error {
+			ran = true
+			return insert(1)(ctx, tx)
+		},
+		insert(2),
+	)
+	cancel()
+	release()
+	errs := wait()
+
+	if !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("cancelled write returned %v, want %v", errs[0], context.Canceled)
+	}
+	if errs[1] != nil {
+		t.Errorf("write after the cancelled one returned %v, want nil", errs[1])
+	}
+	if ran {
+		t.Error("cancelled write ran")
+	}
+	if got := values(t, db); len(got) != 1 || got[0] != 2 {
+		t.Errorf("table holds %v, want [2]", got)
+	}
+}
+
+func TestWriteBatchFailureReachesEveryWrite(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+
+	release := holdWriter(t, db)
+	wait := queueWrites(t, db, nil,
+		insert(1),
+		// Ending the transaction out from under the batch fails it as a whole
+		func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `ROLLBACK`)
+			return err
+		},
+		insert(3),
+	)
+	release()
+	errs := wait()
+
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("write %d returned nil, want the batch's error", i)
+		}
+	}
+	if got := values(t, db); len(got) != 0 {
+		t.Errorf("table holds %v, want nothing", got)
+	}
+}
+
+func TestWriteWithoutQueue(t *testing.T) {
+	opts := DefaultOptions()
+	opts.WriteQueueSize = 0
+	db := newTestDB(t, opts)
+	ctx := context.Background()
+
+	if err := db.write(ctx, insert(1)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	failure := errors.New("write failed")
+	err := db.write(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := insert(2)(ctx, tx); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("failed write returned %v, want %v", err, failure)
+	}
+	if got := values(t, db); len(got) != 1 || got[0] != 1 {
+		t.Errorf("table holds %v, want [1]", got)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	db := newTestDB(t, DefaultOptions())
+	db.Close()
+
+	if err := db.write(context.Background(), insert(1)); !errors.Is(err, ErrClosed) {
+		t.Errorf("write after close returned %v, want %v", err, ErrClosed)
+	}
+}
+
+// readOnlyInsert returns a write that adds v to t on a connection switched
+// to read-only for the duration, so it fails the way a read-only disk would
+func readOnlyInsert(v int) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `PRAGMA query_only = ON`); err != nil {
+			return err
+		}
+		defer tx.ExecContext(ctx, `PRAGMA query_only = OFF`)
+		return insert(v)(ctx, tx)
+	}
+}
+
+func TestCheckWriteReportsFailedWrites(t *testing.T) {
+	for _, size := range []int{DefaultOptions().WriteQueueSize, 0} {
+		opts := DefaultOptions()
+		opts.WriteQueueSize = size
+		db := newTestDB(t, opts)
+		ctx := context.Background()
+
+		if err := db.write(ctx, readOnlyInsert(1)); err == nil {
+			t.Fatal("read-only write succeeded")
+		}
+		// The probe's own empty write mustn't hide the failure
+		for range 2 {
+			if err := db.CheckWrite(ctx); err == nil {
+				t.Errorf("queue size %d: check passed after a failed write", size)
+			}
+		}
+
+		if err := db.write(ctx, insert(1)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if err := db.CheckWrite(ctx); err != nil {
+			t.Errorf("queue size %d: check failed after a successful write: %v", size, err)
+		}
+
+		// Constraint violations and writes refused by their own checks
+		// don't count as failures
+		if err := db.write(ctx, insert(1)); err == nil {
+			t.Fatal("duplicate write succeeded")
+		}
+		if err := db.write(ctx, func(context.Context, *sql.Tx) error { return ErrPlayerNotFound }); err == nil {
+			t.Fatal("refused write succeeded")
+		}
+		if err := db.CheckWrite(ctx); err != nil {
+			t.Errorf("queue size %d: check failed after a refused write: %v", size, err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rayhanadev/2048/config"
+	"github.com/rayhanadev/2048/storage"
+)
+
+const tournamentUsage = "usage: tournament list | create [-rounds n] [-attempts n] [-seeds a,b,c] <name> <start> <end> | standings <name> | finalize"
+
+func tournamentCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(tournamentUsage)
+	}
+
+	db, err := openDB(ctx, cfg, dbWrite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		return listTournaments(ctx, db)
+
+	case "create":
+		return createTournament(ctx, db, args[1:])
+
+	case "standings":
+		if len(args) != 2 {
+			return errors.New("usage: tournament standings <name>")
+		}
+		t, err := db.GetTournamentByName(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[1], err)
+		}
+		standings, err := db.GetTournamentStandings(ctx, t, storage.TournamentResultsSize)
+		if err != nil {
+			return err
+		}
+		for _, s := range standings {
+			rounds := make([]string, len(s.RoundScores))
+			for i, score := range s.RoundScores {
+				rounds[i] = strconv.Itoa(score)
+			}
+			fmt.Printf("%4d  %-20s %8d  %s\n", s.Rank, s.Username, s.Total, strings.Join(rounds, " / "))
+		}
+		return nil
+
+	case "finalize":
+		finalized, err := db.FinalizeDueTournaments(ctx, operator())
+		if err != nil {
+			return err
+		}
+		if len(finalized) == 0 {
+			fmt.Println("No ended tournaments waiting to be finalized")
+		}
+		for _, t := range finalized {
+			fmt.Printf("Finalized tournament %s\n", t.Name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown tournament command %q", args[0])
+}
+
+func createTournament(ctx context.Context, db *storage.DB, args []string) error {
+	fs := flag.NewFlagSet("tournament create", flag.ContinueOnError)
+	rounds := fs.Int("rounds", 3, "number of rounds, each with a random seed")
+	attempts := fs.Int("attempts", 3, "attempts each player gets per round")
+	seedList := fs.String("seeds", "", "comma-separated round seeds, overriding -rounds")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 3 {
+		return errors.New("usage: tournament create [-rounds n] [-attempts n] [-seeds a,b,c] <name> <start> <end>")
+	}
+
+	start, err := parseSeasonTime(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	end, err := parseSeasonTime(fs.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	var seeds []int64
+	if *seedList != "" {
+		for _, field := range strings.Split(*seedList, ",") {
+			seed, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid seed %q", field)
+			}
+			seeds = append(seeds, seed)
+		}
+	} else {
+		if *rounds < 1 || *rounds > storage.MaxTournamentRounds {
+			return fmt.Errorf("-rounds must be 1-%d", storage.MaxTournamentRounds)
+		}
+		for range *rounds {
+			seeds = append(seeds, randomSeed())
+		}
+	}
+
+	t, err := db.CreateTournament(ctx, fs.Arg(0), start, end, *attempts, seeds, operator())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created tournament %s (#%d) with %d rounds of %d attempts from %s to %s\n", t.Name, t.ID,
+		t.Rounds(), t.Attempts, t.StartsAt.Format(time.DateTime), t.EndsAt.Format(time.DateTime))
+	return nil
+}
+
+// randomSeed returns a non-zero seed, since zero means an unseeded game
+func randomSeed() int64 {
+	var b [8]byte
+	for {
+		rand.Read(b[:])
+		if seed := int64(binary.LittleEndian.Uint64(b[:]) >> 1); seed != 0 {
+			return seed
+		}
+	}
+}
+
+func listTournaments(ctx context.Context, db *storage.DB) error {
+	tournaments, err := db.GetTournaments(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tournaments) == 0 {
+		fmt.Println("No tournaments")
+		return nil
+	}
+
+	now := time.Now()
+	for _, t := range tournaments {
+		fmt.Printf("%4d  %-20s %s  %s  %d×%d  %s\n", t.ID, t.Name,
+			t.StartsAt.Format(time.DateTime), t.EndsAt.Format(time.DateTime),
+			t.Rounds(), t.Attempts, t.Status(now))
+	}
+	return nil
+}
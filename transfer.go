@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rayhanadev/2048/config"
+	"github.com/rayhanadev/2048/storage"
+)
+
+func backupCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: backup <file>")
+	}
+
+	db, err := openDB(ctx, cfg, dbRead)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := db.Backup(ctx, args[0]); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	fmt.Println("Backup written to", args[0])
+	return nil
+}
+
+func exportCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "jsonl", "output format: jsonl or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: export [-format jsonl|csv] <dir>")
+	}
+	if *format != "jsonl" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	dir := fs.Arg(0)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	db, err := openDB(ctx, cfg, dbRead)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, table := range storage.ExportTables {
+		path := filepath.Join(dir, table+"."+*format)
+		n, err := exportTable(ctx, db, table, path, *format)
+		if err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+		fmt.Printf("Exported %d rows to %s\n", n, path)
+	}
+	return nil
+}
+
+func exportTable(ctx context.Context, db *storage.DB, table, path, format string) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	var csvw *csv.Writer
+	if format == "csv" {
+		csvw = csv.NewWriter(w)
+	}
+	enc := json.NewEncoder(w)
+
+	count := 0
+	err = db.ExportRows(ctx, table, func(columns []string, values []any) error {
+		count++
+		if csvw == nil {
+			row := make(map[string]any, len(columns))
+			for i, col := range columns {
+				row[col] = values[i]
+			}
+			return enc.Encode(row)
+		}
+
+		if count == 1 {
+			if err := csvw.Write(columns); err != nil {
+				return err
+			}
+		}
+		record := make([]string, len(values))
+		for i, v := range values {
+			if v != nil {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		return csvw.Write(record)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if csvw != nil {
+		csvw.Flush()
+		if err := csvw.Error(); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return count, f.Close()
+}
+
+func importCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "jsonl", "input format: jsonl or csv")
+	onConflict := fs.String("on-conflict", "skip", "what to do with existing rows: skip, replace or fail")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: import [-format jsonl|csv] [-on-conflict skip|replace|fail] <dir>")
+	}
+	if *format != "jsonl" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	db, err := openDB(ctx, cfg, dbWrite)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, table := range storage.ExportTables {
+		path := filepath.Join(fs.Arg(0), table+"."+*format)
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Println("Skipping", table, "(no file)")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var next func() (map[string]any, error)
+		if *format == "csv" {
+			columns, err := db.Columns(ctx, table)
+			if err != nil {
+				f.Close()
+				return err
+			}
+			next = csvRows(f, columns)
+		} else {
+			next = jsonlRows(f)
+		}
+
+		result, err := db.ImportRows(ctx, table, storage.ConflictPolicy(*onConflict), next)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s: %d inserted, %d skipped\n", table, result.Inserted, result.Skipped)
+	}
+	return nil
+}
+
+// jsonlRows reads one JSON object per line, keeping integers exact
+func jsonlRows(r io.Reader) func() (map[string]any, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.UseNumber()
+	line := 0
+	return func() (map[string]any, error) {
+		var row map[string]any
+		if err := dec.Decode(&row); err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line+1, err)
+		}
+		line++
+
+		for k, v := range row {
+			if n, ok := v.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					row[k] = i
+				} else if f, err := n.Float64(); err == nil {
+					row[k] = f
+				}
+			}
+		}
+		return row, nil
+	}
+}
+
+// csvRows reads a CSV file whose first record holds the column names.
+// Empty fields become NULL, or an empty string in NOT NULL columns, and the
+// rest are converted to the type declared for their column, so a text column
+// keeps "007" as written.
+func csvRows(r io.Reader, columns map[string]storage.Column) func() (map[string]any, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	var header []string
+	return func() (map[string]any, error) {
+		if header == nil {
+			h, err := cr.Read()
+			if err == io.EOF {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			header = h
+		}
+
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(header))
+		for i, col := range header {
+			v := record[i]
+			switch {
+			case v == "" && columns[col].NotNull:
+				row[col] = ""
+			case v == "":
+				row[col] = nil
+			default:
+				row[col] = csvValue(v, columns[col].Type)
+			}
+		}
+		return row, nil
+	}
+}
+
+// csvValue converts a CSV field following SQLite's type affinity rules for
+// the column's declared type
+func csvValue(v, declared string) any {
+	declared = strings.ToUpper(declared)
+	switch {
+	case strings.Contains(declared, "INT"):
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case strings.Contains(declared, "CHAR"), strings.Contains(declared, "CLOB"), strings.Contains(declared, "TEXT"),
+		strings.Contains(declared, "BLOB"), declared == "":
+		return v
+	case strings.Contains(declared, "REAL"), strings.Contains(declared, "FLOA"), strings.Contains(declared, "DOUB"):
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	default:
+		// NUMERIC affinity, used by DATETIME and BOOLEAN columns
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/achievements"
+	"github.com/rayhanadev/2048/game"
+	"github.com/rayhanadev/2048/storage"
+)
+
+// toastDuration is how long an unlock notification stays on screen
+const toastDuration = 4 * time.Second
+
+type toast struct {
+	id   int
+	text string
+}
+
+type toastExpiredMsg int
+
+// gameEndStatsMsg carries the totals the game end achievements depend on
+type gameEndStatsMsg struct {
+	game          game.Game
+	gamesPlayed   int
+	currentStreak int
+}
+
+// achievementSavedMsg reports whether an unlock was stored
+type achievementSavedMsg struct {
+	err error
+}
+
+// achievementsMsg carries the achievements screen's data
+type achievementsMsg struct {
+	unlocked []storage.Achievement
+	err      error
+}
+
+// newTracker creates an achievement tracker seeded with what the player already unlocked
+func newTracker(unlockedIDs []string) *achievements.Tracker {
+	return achievements.NewTracker(achievements.DefaultRules(), unlockedIDs)
+}
+
+// trackGameEnd looks up the player's totals, then feeds the finished game to the tracker.
+// If the lookup fails the game is still checked, just without the totals.
+func (m Model) trackGameEnd(finished game.Game) tea.Cmd {
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		msg := gameEndStatsMsg{game: finished}
+		if stats, err := m.db.GetPlayerStats(ctx, playerID, 0); err == nil {
+			msg.gamesPlayed = stats.GamesPlayed
+			msg.currentStreak = stats.CurrentStreak
+		}
+		return msg
+	})
+}
+
+// unlock persists newly unlocked achievements and shows a toast for each
+func (m *Model) unlock(rules []achievements.Rule) []tea.Cmd {
+	var cmds []tea.Cmd
+	for _, r := range rules {
+		if m.player != nil {
+			playerID, achievementID := m.player.ID, r.ID
+			cmds = append(cmds, m.query(func(ctx context.Context) tea.Msg {
+				return achievementSavedMsg{err: m.db.UnlockAchievement(ctx, playerID, achievementID)}
+			}))
+		}
+		cmds = append(cmds, m.showToast("🏅 Achievement unlocked: "+r.Name))
+	}
+	return cmds
+}
+
+// showToast adds a notification above the board and schedules its removal
+func (m *Model) showToast(text string) tea.Cmd {
+	m.toastSeq++
+	id := m.toastSeq
+	m.toasts = append(m.toasts, toast{id: id, text: text})
+	return tea.Tick(toastDuration, func(time.Time) tea.Msg {
+		return toastExpiredMsg(id)
+	})
+}
+
+func (m *Model) dismissToast(id int) {
+	for i, t := range m.toasts {
+		if t.id == id {
+			m.toasts = append(m.toasts[:i:i], m.toasts[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m Model) renderToasts() string {
+	if len(m.toasts) == 0 {
+		return ""
+	}
+
+	var lines []string
+	for _, t := range m.toasts {
+		lines = append(lines, ToastStyle.Render(t.text))
+	}
+	return lipgloss.JoinVertical(lipgloss.Center, lines...)
+}
+
+func (m Model) openAchievements() (tea.Model, tea.Cmd) {
+	m.unlocked = nil
+	m.err = nil
+	m.loading = false
+	m.state = StateAchievements
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.loading = true
+	playerID := m.player.ID
+	return m, m.query(func(ctx context.Context) tea.Msg {
+		unlocked, err := m.db.GetAchievements(ctx, playerID)
+		return achievementsMsg{unlocked: unlocked, err: err}
+	})
+}
+
+func (m Model) handleAchievementsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "c", "enter", " ":
+		m.loading = false
+		if m.game.GameOver {
+			m.state = StateGameOver
+		} else {
+			m.state = StatePlaying
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m Model) renderAchievements() string {
+	title := TitleStyle.Render("🏅 Achievements 🏅")
+
+	unlockedAt := make(map[string]time.Time, len(m.unlocked))
+	for _, a := range m.unlocked {
+		unlockedAt[a.AchievementID] = a.UnlockedAt
+	}
+
+	rules := m.achievements.Rules()
+	var rows []string
+	for _, r := range rules {
+		if at, ok := unlockedAt[r.ID]; ok {
+			rows = append(rows, fmt.Sprintf("%s %-16s %-40s %s",
+				"★",
+				r.Name,
+				r.Description,
+				at.Format("2006-01-02")))
+		} else {
+			rows = append(rows, AchievementLockedStyle.Render(fmt.Sprintf("%s %-16s %-40s",
+				"☆",
+				r.Name,
+				r.Description)))
+		}
+	}
+
+	summary := fmt.Sprintf("%d / %d unlocked", len(unlockedAt), len(rules))
+	switch {
+	case m.loading:
+		summary = renderLoading()
+	case m.err != nil:
+		summary = ErrorStyle.Render(m.err.Error())
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(append([]string{summary, ""}, rows...), "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	footer := InstructionsStyle.Render("Press Enter or C to return")
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
@@ -0,0 +1,640 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+const (
+	// adminPageSize is how many players or scores the admin screens list
+	adminPageSize = 15
+
+	// broadcastDuration is how long a broadcast stays on screen
+	broadcastDuration = 15 * time.Second
+
+	// maxBroadcastLength caps the length of a broadcast message
+	maxBroadcastLength = 120
+
+	// maxBanReasonLength caps the length of a ban reason
+	maxBanReasonLength = 80
+)
+
+// SessionInfo describes a connected session for the admin screen
+type SessionInfo struct {
+	ID          uint64
+	Fingerprint string
+	RemoteIP    string
+	StartedAt   time.Time
+	LastActive  time.Time
+}
+
+// AdminTools are the server operations available from the admin screen
+type AdminTools interface {
+	// Sessions lists the connected sessions, oldest first
+	Sessions() []SessionInfo
+	// Broadcast shows text to every connected player and returns how many received it
+	Broadcast(text string) int
+	// DisconnectPlayer closes every session using one of the player's keys
+	// and returns how many were closed
+	DisconnectPlayer(ctx context.Context, playerID int64) (int, error)
+}
+
+// BroadcastMsg is an announcement from an admin shown to every connected player
+type BroadcastMsg struct {
+	Text string
+}
+
+// broadcastExpiredMsg removes a broadcast once it has been shown long enough
+type broadcastExpiredMsg int
+
+type adminTab int
+
+const (
+	adminPlayers adminTab = iota
+	adminSessions
+	adminTabCount
+)
+
+// adminInput is what the text input is being used for on the admin screen
+type adminInput int
+
+const (
+	adminInputNone adminInput = iota
+	adminInputSearch
+	adminInputRename
+	adminInputBan
+	adminInputBroadcast
+)
+
+// adminSession is a connected session with the username of its key, if any
+type adminSession struct {
+	SessionInfo
+	Username string
+}
+
+type adminState struct {
+	tab      adminTab
+	input    adminInput
+	search   string
+	players  []storage.PlayerSummary
+	cursor   int
+	detail   *storage.Player
+	scores   []storage.Score
+	score    int
+	confirm  bool
+	sessions []adminSession
+	session  int
+	status   string
+	returnTo AppState
+}
+
+// adminPlayersMsg carries the players matching the search
+type adminPlayersMsg struct {
+	players []storage.PlayerSummary
+	err     error
+}
+
+// adminPlayerMsg carries a player opened from the list and their best scores
+type adminPlayerMsg struct {
+	player *storage.Player
+	scores []storage.Score
+	err    error
+}
+
+// adminSessionsMsg carries the connected sessions
+type adminSessionsMsg struct {
+	sessions []adminSession
+	err      error
+}
+
+// adminDoneMsg reports the outcome of a moderation action
+type adminDoneMsg struct {
+	status string
+	err    error
+}
+
+// adminError turns storage errors from moderation into messages suitable for admins
+func adminError(err error) error {
+	var invalid *storage.UsernameError
+	if errors.As(err, &invalid) ||
+		errors.Is(err, storage.ErrPlayerNotFound) ||
+		errors.Is(err, storage.ErrUsernameTaken) ||
+		errors.Is(err, storage.ErrScoreNotFound) ||
+		errors.Is(err, storage.ErrAlreadyBanned) ||
+		errors.Is(err, storage.ErrNotBanned) {
+		return err
+	}
+	return dbError(err)
+}
+
+// WithAdmin gives the session access to the admin screen
+func (m Model) WithAdmin(tools AdminTools) Model {
+	m.admin = tools
+	return m
+}
+
+// actor identifies the admin in the audit log
+func (m Model) actor() string {
+	return "admin:" + m.fingerprint
+}
+
+func (m Model) openAdmin() (tea.Model, tea.Cmd) {
+	if m.admin == nil {
+		return m, nil
+	}
+
+	m.adminView = adminState{returnTo: m.state}
+	m.err = nil
+	m.state = StateAdmin
+	return m, m.loadAdminPlayers()
+}
+
+func (m *Model) loadAdminPlayers() tea.Cmd {
+	m.loading = true
+	search := m.adminView.search
+	return m.query(func(ctx context.Context) tea.Msg {
+		players, err := m.db.SearchPlayers(ctx, search, adminPageSize)
+		return adminPlayersMsg{players: players, err: err}
+	})
+}
+
+func (m *Model) loadAdminPlayer(playerID int64) tea.Cmd {
+	m.loading = true
+	return m.query(func(ctx context.Context) tea.Msg {
+		player, err := m.db.GetPlayerByID(ctx, playerID)
+		if err != nil {
+			return adminPlayerMsg{err: err}
+		}
+		scores, err := m.db.GetPlayerScores(ctx, playerID, adminPageSize)
+		return adminPlayerMsg{player: player, scores: scores, err: err}
+	})
+}
+
+func (m *Model) loadAdminSessions() tea.Cmd {
+	m.loading = true
+	sessions := m.admin.Sessions()
+	return m.query(func(ctx context.Context) tea.Msg {
+		live := make([]adminSession, len(sessions))
+		for i, s := range sessions {
+			live[i].SessionInfo = s
+			player, err := m.db.GetPlayerByFingerprint(ctx, s.Fingerprint)
+			if err == nil {
+				live[i].Username = player.Username
+			} else if !errors.Is(err, storage.ErrPlayerNotFound) {
+				return adminSessionsMsg{err: err}
+			}
+		}
+		return adminSessionsMsg{sessions: live}
+	})
+}
+
+// adminAction runs a moderation action, reporting status when it succeeds
+func (m *Model) adminAction(status string, fn func(ctx context.Context) error) tea.Cmd {
+	m.loading = true
+	m.err = nil
+	m.adminView.status = ""
+	return m.query(func(ctx context.Context) tea.Msg {
+		if err := fn(ctx); err != nil {
+			return adminDoneMsg{err: err}
+		}
+		return adminDoneMsg{status: status}
+	})
+}
+
+// startAdminInput points the text input at one of the admin fields
+func (m Model) startAdminInput(input adminInput, placeholder, value string, limit int) (tea.Model, tea.Cmd) {
+	m.err = nil
+	m.adminView.input = input
+	m.adminView.status = ""
+	m.textInput.SetValue(value)
+	m.textInput.Placeholder = placeholder
+	m.textInput.CharLimit = limit
+	m.textInput.CursorEnd()
+	return m, textinput.Blink
+}
+
+// stopAdminInput restores the text input for usernames
+func (m Model) stopAdminInput() Model {
+	m.adminView.input = adminInputNone
+	m.textInput.Placeholder = "Enter username"
+	m.textInput.CharLimit = storage.MaxUsernameLength
+	return m
+}
+
+func (m Model) handleAdminInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.adminView.input != adminInputNone {
+		return m.handleAdminTextInput(msg)
+	}
+	if m.adminView.detail != nil {
+		return m.handleAdminPlayerInput(msg)
+	}
+
+	switch msg.String() {
+	case "esc", "A":
+		m.err = nil
+		m.loading = false
+		m.state = m.adminView.returnTo
+		return m, nil
+
+	case "tab", "shift+tab":
+		m.adminView.tab = (m.adminView.tab + 1) % adminTabCount
+		m.adminView.status = ""
+		m.err = nil
+		if m.adminView.tab == adminSessions {
+			return m, m.loadAdminSessions()
+		}
+		return m, m.loadAdminPlayers()
+
+	case "b":
+		return m.startAdminInput(adminInputBroadcast, "Message to everyone", "", maxBroadcastLength)
+	}
+
+	if m.adminView.tab == adminSessions {
+		switch msg.String() {
+		case "up", "k":
+			if m.adminView.session > 0 {
+				m.adminView.session--
+			}
+		case "down", "j":
+			if m.adminView.session < len(m.adminView.sessions)-1 {
+				m.adminView.session++
+			}
+		case "r":
+			return m, m.loadAdminSessions()
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.adminView.cursor > 0 {
+			m.adminView.cursor--
+		}
+	case "down", "j":
+		if m.adminView.cursor < len(m.adminView.players)-1 {
+			m.adminView.cursor++
+		}
+	case "/":
+		return m.startAdminInput(adminInputSearch, "Username prefix", m.adminView.search, storage.MaxUsernameLength)
+	case "enter", " ":
+		if m.loading || len(m.adminView.players) == 0 {
+			return m, nil
+		}
+		m.err = nil
+		m.adminView.status = ""
+		m.adminView.score = 0
+		m.adminView.confirm = false
+		return m, m.loadAdminPlayer(m.adminView.players[m.adminView.cursor].ID)
+	}
+	return m, nil
+}
+
+// handleAdminPlayerInput handles keys on a single player's screen
+func (m Model) handleAdminPlayerInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.adminView.detail
+
+	if m.adminView.confirm {
+		m.adminView.confirm = false
+		if msg.String() != "y" || m.loading || m.adminView.score >= len(m.adminView.scores) {
+			return m, nil
+		}
+		s := m.adminView.scores[m.adminView.score]
+		actor := m.actor()
+		return m, m.adminAction(fmt.Sprintf("Deleted score %d", s.Score), func(ctx context.Context) error {
+			return m.db.DeleteScore(ctx, s.ID, actor)
+		})
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.err = nil
+		m.adminView.detail = nil
+		m.adminView.status = ""
+		return m, m.loadAdminPlayers()
+
+	case "up", "k":
+		if m.adminView.score > 0 {
+			m.adminView.score--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.adminView.score < len(m.adminView.scores)-1 {
+			m.adminView.score++
+		}
+		return m, nil
+	}
+
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "n":
+		return m.startAdminInput(adminInputRename, "New username", p.Username, storage.MaxUsernameLength)
+
+	case "x":
+		if !p.BannedAt.Valid {
+			return m.startAdminInput(adminInputBan, "Reason for the ban", "", maxBanReasonLength)
+		}
+		playerID, actor := p.ID, m.actor()
+		return m, m.adminAction("Unbanned "+p.Username, func(ctx context.Context) error {
+			return m.db.UnbanPlayer(ctx, playerID, actor)
+		})
+
+	case "d":
+		if len(m.adminView.scores) > 0 {
+			m.adminView.confirm = true
+			m.adminView.status = ""
+		}
+	}
+	return m, nil
+}
+
+// handleAdminTextInput edits the search, a new username, a ban reason or a broadcast
+func (m Model) handleAdminTextInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.err = nil
+		m = m.stopAdminInput()
+		return m, nil
+
+	case tea.KeyEnter:
+		value := strings.TrimSpace(m.textInput.Value())
+		input := m.adminView.input
+		actor := m.actor()
+		m = m.stopAdminInput()
+
+		switch input {
+		case adminInputSearch:
+			m.adminView.search = value
+			m.adminView.cursor = 0
+			return m, m.loadAdminPlayers()
+
+		case adminInputRename:
+			p := m.adminView.detail
+			if value == "" || value == p.Username {
+				return m, nil
+			}
+			return m, m.adminAction(fmt.Sprintf("Renamed %s to %s", p.Username, value), func(ctx context.Context) error {
+				return m.db.AdminRename(ctx, p.ID, value, actor)
+			})
+
+		case adminInputBan:
+			p := m.adminView.detail
+			if value == "" {
+				m.err = errors.New("a ban needs a reason")
+				return m, nil
+			}
+			return m, m.adminAction("Banned "+p.Username, func(ctx context.Context) error {
+				if err := m.db.BanPlayer(ctx, p.ID, value, actor); err != nil {
+					return err
+				}
+				_, err := m.admin.DisconnectPlayer(ctx, p.ID)
+				return err
+			})
+
+		case adminInputBroadcast:
+			if value == "" {
+				return m, nil
+			}
+			m.loading = true
+			m.adminView.status = ""
+			return m, m.query(func(ctx context.Context) tea.Msg {
+				sent := m.admin.Broadcast(value)
+				err := m.db.RecordAudit(ctx, actor, "broadcast", 0, map[string]any{
+					"text":       value,
+					"recipients": sent,
+				})
+				return adminDoneMsg{status: fmt.Sprintf("Broadcast sent to %d sessions", sent), err: err}
+			})
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleAdminResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.state != StateAdmin {
+		return m, nil
+	}
+	m.loading = false
+
+	switch msg := msg.(type) {
+	case adminPlayersMsg:
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.adminView.players = msg.players
+		if m.adminView.cursor >= len(msg.players) {
+			m.adminView.cursor = max(len(msg.players)-1, 0)
+		}
+
+	case adminPlayerMsg:
+		if msg.err != nil {
+			m.err = adminError(msg.err)
+			return m, nil
+		}
+		m.adminView.detail = msg.player
+		m.adminView.scores = msg.scores
+		if m.adminView.score >= len(msg.scores) {
+			m.adminView.score = max(len(msg.scores)-1, 0)
+		}
+
+	case adminSessionsMsg:
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.adminView.sessions = msg.sessions
+		if m.adminView.session >= len(msg.sessions) {
+			m.adminView.session = max(len(msg.sessions)-1, 0)
+		}
+
+	case adminDoneMsg:
+		if msg.err != nil {
+			m.err = adminError(msg.err)
+			return m, nil
+		}
+		m.adminView.status = msg.status
+		if m.adminView.detail != nil {
+			return m, m.loadAdminPlayer(m.adminView.detail.ID)
+		}
+	}
+	return m, nil
+}
+
+// showBroadcast displays an admin announcement until it expires
+func (m Model) showBroadcast(text string) (tea.Model, tea.Cmd) {
+	m.broadcastSeq++
+	m.broadcast = text
+	seq := m.broadcastSeq
+	return m, tea.Tick(broadcastDuration, func(time.Time) tea.Msg {
+		return broadcastExpiredMsg(seq)
+	})
+}
+
+func (m Model) renderBroadcast() string {
+	if m.broadcast == "" {
+		return ""
+	}
+	return BannerStyle.Render("📣 " + m.broadcast)
+}
+
+func (m Model) renderAdmin() string {
+	title := TitleStyle.Render("🛡  Admin")
+
+	tabNames := []string{"Players", "Sessions"}
+	tabNames[m.adminView.tab] = LeaderboardHighlightStyle.Render(tabNames[m.adminView.tab])
+	rows := []string{strings.Join(tabNames, " • "), ""}
+
+	var footer string
+	switch {
+	case m.adminView.tab == adminSessions:
+		rows = append(rows, m.renderAdminSessions()...)
+		footer = "↑/↓: Select • R: Refresh • B: Broadcast • Tab: Players • Esc: Back"
+	case m.adminView.detail != nil:
+		rows = append(rows, m.renderAdminPlayer()...)
+		footer = "↑/↓: Select score • D: Delete score • N: Rename • X: Ban/Unban • Esc: Players"
+	default:
+		rows = append(rows, m.renderAdminPlayers()...)
+		footer = "↑/↓: Select • Enter: Open • /: Search • B: Broadcast • Tab: Sessions • Esc: Back"
+	}
+
+	switch m.adminView.input {
+	case adminInputSearch:
+		rows = append(rows, "", "Search players:", m.textInput.View())
+	case adminInputRename:
+		rows = append(rows, "", "New username:", m.textInput.View())
+	case adminInputBan:
+		rows = append(rows, "", "Ban reason:", m.textInput.View())
+	case adminInputBroadcast:
+		rows = append(rows, "", "Broadcast to everyone connected:", m.textInput.View())
+	}
+	if m.adminView.input != adminInputNone {
+		footer = "Press Enter to confirm • Esc to cancel"
+	}
+
+	if m.adminView.confirm {
+		s := m.adminView.scores[m.adminView.score]
+		rows = append(rows, "", ErrorStyle.Render(fmt.Sprintf("Delete the %d score from %s? Press Y to confirm.",
+			s.Score, s.CreatedAt.Format("2006-01-02 15:04"))))
+	}
+
+	switch {
+	case m.loading:
+		rows = append(rows, "", renderLoading())
+	case m.err != nil:
+		rows = append(rows, "", ErrorStyle.Render(m.err.Error()))
+	case m.adminView.status != "":
+		rows = append(rows, "", StatValueStyle.Render(m.adminView.status))
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, InstructionsStyle.Render(footer))
+}
+
+func (m Model) renderAdminPlayers() []string {
+	search := "All players"
+	if m.adminView.search != "" {
+		search = fmt.Sprintf("Usernames starting with %q", m.adminView.search)
+	}
+	rows := []string{StatLabelStyle.UnsetWidth().Render(search), ""}
+
+	for i, p := range m.adminView.players {
+		row := fmt.Sprintf("%-20s %5d games  best %-7d", truncateString(p.Username, 20), p.Games, p.BestScore)
+		if p.BannedAt.Valid {
+			row += " banned"
+		} else if p.Hidden {
+			row += " hidden"
+		}
+		if i == m.adminView.cursor {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+	if len(m.adminView.players) == 0 && !m.loading && m.err == nil {
+		rows = append(rows, "No players found.")
+	}
+	return rows
+}
+
+func (m Model) renderAdminPlayer() []string {
+	p := m.adminView.detail
+	rows := []string{
+		fmt.Sprintf("%s (#%d) • joined %s", StatValueStyle.Render(p.Username), p.ID, p.CreatedAt.Format("2006-01-02")),
+		StatLabelStyle.UnsetWidth().Render(p.PubkeyFingerprint),
+	}
+	if p.BannedAt.Valid {
+		rows = append(rows, ErrorStyle.Render(fmt.Sprintf("Banned %s: %s", p.BannedAt.Time.Format("2006-01-02"), p.BanReason)))
+	}
+	if p.Hidden {
+		rows = append(rows, "Hidden from leaderboards")
+	}
+
+	rows = append(rows, "", fmt.Sprintf("%-8s %-6s %-6s %-9s %s", "Score", "Tile", "Moves", "Time", "Played"))
+	for i, s := range m.adminView.scores {
+		row := fmt.Sprintf("%-8d %-6d %-6d %-9s %s", s.Score, s.MaxTile, s.Moves, formatDuration(s.Duration), s.CreatedAt.Format("2006-01-02 15:04"))
+		if i == m.adminView.score {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+	if len(m.adminView.scores) == 0 && !m.loading {
+		rows = append(rows, "No scores.")
+	}
+	return rows
+}
+
+func (m Model) renderAdminSessions() []string {
+	rows := []string{fmt.Sprintf("  %-6s %-20s %-16s %-10s %s", "ID", "Player", "Address", "Connected", "Idle")}
+	now := time.Now()
+	for i, s := range m.adminView.sessions {
+		name := s.Username
+		if name == "" {
+			name = "(new player)"
+		}
+		row := fmt.Sprintf("%-6d %-20s %-16s %-10s %s", s.ID, truncateString(name, 20), s.RemoteIP,
+			formatDuration(now.Sub(s.StartedAt)), formatDuration(now.Sub(s.LastActive)))
+		if i == m.adminView.session {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+	if len(m.adminView.sessions) == 0 && !m.loading {
+		rows = append(rows, "No sessions.")
+	}
+	return rows
+}
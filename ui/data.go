@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+// dbTimeout bounds each database call made on behalf of a session
+const dbTimeout = 5 * time.Second
+
+// query runs fn outside the Update loop. The context is cancelled when the
+// session disconnects or after dbTimeout, whichever comes first.
+func (m Model) query(fn func(ctx context.Context) tea.Msg) tea.Cmd {
+	parent := m.ctx
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(parent, dbTimeout)
+		defer cancel()
+		return fn(ctx)
+	}
+}
+
+// dbError turns an unexpected storage error into a message suitable for players
+func dbError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || storage.IsBusy(err) {
+		return errors.New("the server is busy, please try again")
+	}
+	return errors.New("something went wrong, please try again")
+}
+
+// renderLoading is shown in place of content that is still being fetched
+func renderLoading() string {
+	return StatLabelStyle.Render("Loading…")
+}
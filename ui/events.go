@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/rayhanadev/2048/metrics"
+)
+
+// logger returns the session's logger, which tags every line with the session ID
+func (m Model) logger() *log.Logger {
+	return log.FromContext(m.ctx)
+}
+
+// mode labels the game being played in logs and metrics
+func (m Model) mode() string {
+	switch {
+	case m.guest:
+		return metrics.ModeGuest
+	case m.run != nil:
+		return metrics.ModeTournament
+	}
+	return metrics.ModeNormal
+}
+
+// recordMove counts a move that changed the board, and the game as started
+// on its first move
+func (m Model) recordMove() {
+	metrics.Moves.Inc()
+	if m.game.Moves == 1 {
+		metrics.GamesStarted.WithLabelValues(m.mode()).Inc()
+		m.logger().Info("Game started", "mode", m.mode(), "seed", m.game.Seed)
+	}
+}
+
+// recordGameEnd counts and logs a finished game and its score
+func (m Model) recordGameEnd() {
+	mode := m.mode()
+	metrics.GamesFinished.WithLabelValues(mode).Inc()
+	metrics.FinalScores.WithLabelValues(mode).Observe(float64(m.game.Score))
+	metrics.MaxTiles.WithLabelValues(mode).Observe(float64(m.game.MaxTile()))
+
+	m.logger().Info("Game over",
+		"mode", mode,
+		"score", m.game.Score,
+		"max_tile", m.game.MaxTile(),
+		"moves", m.game.Moves,
+		"won", m.game.Won,
+		"duration", m.game.Duration().Round(time.Second),
+	)
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+type friendsState struct {
+	following []storage.Friend
+	cursor    int
+	adding    bool
+	returnTo  AppState
+}
+
+// friendError turns storage errors from following into messages suitable for players
+func friendError(err error) error {
+	if errors.Is(err, storage.ErrPlayerNotFound) ||
+		errors.Is(err, storage.ErrFollowSelf) ||
+		errors.Is(err, storage.ErrAlreadyFollowing) ||
+		errors.Is(err, storage.ErrNotFollowing) ||
+		errors.Is(err, storage.ErrTooManyFollows) {
+		return err
+	}
+	return dbError(err)
+}
+
+// friendsMsg carries the players someone follows
+type friendsMsg struct {
+	following []storage.Friend
+	err       error
+}
+
+// followedMsg reports whether a player was followed
+type followedMsg struct {
+	err error
+}
+
+// unfollowedMsg reports whether a player was unfollowed
+type unfollowedMsg struct {
+	err error
+}
+
+func (m Model) openFriends() (tea.Model, tea.Cmd) {
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.friends = friendsState{returnTo: m.state}
+	m.err = nil
+	m.state = StateFriends
+	return m, m.loadFriends()
+}
+
+func (m *Model) loadFriends() tea.Cmd {
+	m.loading = true
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		following, err := m.db.GetFollowing(ctx, playerID)
+		return friendsMsg{following: following, err: err}
+	})
+}
+
+func (m Model) handleFriendsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.friends.adding {
+		return m.handleFollowInput(msg)
+	}
+
+	switch msg.String() {
+	case "esc", "f":
+		m.err = nil
+		m.loading = false
+		m.state = m.friends.returnTo
+		return m, nil
+
+	case "up", "k":
+		if m.friends.cursor > 0 {
+			m.friends.cursor--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.friends.cursor < len(m.friends.following)-1 {
+			m.friends.cursor++
+		}
+		return m, nil
+	}
+
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "a":
+		m.err = nil
+		m.friends.adding = true
+		m.textInput.SetValue("")
+		m.textInput.Placeholder = "Username to follow"
+		return m, textinput.Blink
+
+	case "x":
+		if len(m.friends.following) == 0 {
+			return m, nil
+		}
+		m.loading = true
+		m.err = nil
+		playerID, followeeID := m.player.ID, m.friends.following[m.friends.cursor].PlayerID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			return unfollowedMsg{err: m.db.Unfollow(ctx, playerID, followeeID)}
+		})
+	}
+
+	return m, nil
+}
+
+// handleFollowInput edits the username to follow
+func (m Model) handleFollowInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.err = nil
+		m.friends.adding = false
+		m.textInput.Placeholder = "Enter username"
+		return m, nil
+
+	case tea.KeyEnter:
+		username := strings.TrimSpace(m.textInput.Value())
+		if username == "" {
+			return m, nil
+		}
+
+		m.loading = true
+		m.err = nil
+		playerID := m.player.ID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			_, err := m.db.Follow(ctx, playerID, username)
+			return followedMsg{err: err}
+		})
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleFriendsResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.state != StateFriends {
+		return m, nil
+	}
+	m.loading = false
+
+	switch msg := msg.(type) {
+	case friendsMsg:
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.friends.following = msg.following
+		if m.friends.cursor >= len(msg.following) {
+			m.friends.cursor = max(len(msg.following)-1, 0)
+		}
+
+	case followedMsg:
+		if msg.err != nil {
+			m.err = friendError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		m.friends.adding = false
+		m.textInput.Placeholder = "Enter username"
+		return m, m.loadFriends()
+
+	case unfollowedMsg:
+		if msg.err != nil {
+			m.err = friendError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		return m, m.loadFriends()
+	}
+	return m, nil
+}
+
+func (m Model) renderFriends() string {
+	title := TitleStyle.Render("👥 Friends")
+
+	var rows []string
+	for i, f := range m.friends.following {
+		best := fmt.Sprintf("best %d", f.BestScore)
+		if f.Hidden {
+			best = "hidden"
+		}
+
+		row := fmt.Sprintf("%-20s %-12s followed %s", truncateString(f.Username, 20), best, f.FollowedAt.Format("2006-01-02"))
+		if i == m.friends.cursor && !m.friends.adding {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+
+	if len(m.friends.following) == 0 && !m.loading && m.err == nil {
+		rows = append(rows, "You aren't following anyone yet.",
+			"Follow players to compare scores on the friends leaderboard.")
+	}
+
+	if m.friends.adding {
+		rows = append(rows, "", "Follow a player:", m.textInput.View())
+	}
+
+	if m.loading {
+		rows = append(rows, "", renderLoading())
+	}
+
+	if m.err != nil {
+		rows = append(rows, "", ErrorStyle.Render(m.err.Error()))
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	footer := "↑/↓: Select • A: Follow • X: Unfollow • Esc: Back"
+	if m.friends.adding {
+		footer = "Press Enter to follow • Esc to cancel"
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, InstructionsStyle.Render(footer))
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// guestMenuKeys lists the screens a guest can reach
+const guestMenuKeys = "B: Leaderboard"
+
+// guestRunSavedMsg carries the claim code for a game a guest finished
+type guestRunSavedMsg struct {
+	code      string
+	expiresAt time.Time
+	err       error
+}
+
+// AsGuest makes the model play without an account. Guests' games stay off
+// the leaderboard, but each finished game gets a code the player can use to
+// claim it once they connect with a key.
+func (m Model) AsGuest() Model {
+	m.guest = true
+	return m
+}
+
+// saveGuestRun stores a guest's finished game for claiming later
+func (m Model) saveGuestRun() tea.Cmd {
+	finished := *m.game
+	duration := m.game.Duration()
+	return m.query(func(ctx context.Context) tea.Msg {
+		code, expiresAt, err := m.db.SaveGuestRun(ctx, finished.Score, finished.MaxTile(), finished.Moves, duration)
+		return guestRunSavedMsg{code: code, expiresAt: expiresAt, err: err}
+	})
+}
+
+func (m Model) handleGuestRunSaved(msg guestRunSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.logger().Error("Failed to save guest run", "error", msg.err)
+		return m, m.showToast("⚠️  Couldn't save your game for claiming: " + dbError(msg.err).Error())
+	}
+	m.claimCode = msg.code
+	m.claimExpires = msg.expiresAt
+	return m, nil
+}
+
+// renderClaim explains how a guest can keep the score of the game they finished
+func (m Model) renderClaim() string {
+	lines := []string{"Playing as a guest, so this score isn't on the leaderboard."}
+	if m.claimCode != "" {
+		lines = append(lines,
+			"To keep it, connect with an SSH key and run:",
+			StatValueStyle.Render("claim "+m.claimCode),
+			fmt.Sprintf("before %s UTC", m.claimExpires.UTC().Format("2006-01-02 15:04")),
+		)
+	}
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Align(lipgloss.Center).
+		Render(lipgloss.JoinVertical(lipgloss.Center, lines...))
+}
@@ -0,0 +1,217 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+type keysState struct {
+	keys        []storage.PlayerKey
+	cursor      int
+	code        string
+	codeExpires time.Time
+	returnTo    AppState
+}
+
+// linkError turns storage errors from key linking into messages suitable for players
+func linkError(err error) error {
+	if errors.Is(err, storage.ErrInvalidPairingCode) ||
+		errors.Is(err, storage.ErrKeyAlreadyLinked) ||
+		errors.Is(err, storage.ErrKeyNotFound) ||
+		errors.Is(err, storage.ErrLastKey) {
+		return err
+	}
+	return dbError(err)
+}
+
+// keysMsg carries the player's linked keys
+type keysMsg struct {
+	keys []storage.PlayerKey
+	err  error
+}
+
+// pairingCodeMsg carries a newly created pairing code
+type pairingCodeMsg struct {
+	code    string
+	expires time.Time
+	err     error
+}
+
+// keyRevokedMsg reports whether a key was revoked
+type keyRevokedMsg struct {
+	err error
+}
+
+func (m Model) openKeys() (tea.Model, tea.Cmd) {
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.keys = keysState{returnTo: m.state}
+	m.err = nil
+	m.state = StateKeys
+	return m, m.loadKeys()
+}
+
+func (m *Model) loadKeys() tea.Cmd {
+	m.loading = true
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		keys, err := m.db.GetPlayerKeys(ctx, playerID)
+		return keysMsg{keys: keys, err: err}
+	})
+}
+
+func (m Model) handleKeysInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "u":
+		m.err = nil
+		m.loading = false
+		m.state = m.keys.returnTo
+		return m, nil
+
+	case "up", "k":
+		if m.keys.cursor > 0 {
+			m.keys.cursor--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.keys.cursor < len(m.keys.keys)-1 {
+			m.keys.cursor++
+		}
+		return m, nil
+	}
+
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "g":
+		m.loading = true
+		playerID := m.player.ID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			code, expires, err := m.db.CreatePairingCode(ctx, playerID)
+			return pairingCodeMsg{code: code, expires: expires, err: err}
+		})
+
+	case "x":
+		if len(m.keys.keys) == 0 {
+			return m, nil
+		}
+		selected := m.keys.keys[m.keys.cursor]
+		if selected.Fingerprint == m.fingerprint {
+			m.err = errors.New("connect with a different key to revoke the one you're using")
+			return m, nil
+		}
+		m.loading = true
+		playerID := m.player.ID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			return keyRevokedMsg{err: m.db.RevokeKey(ctx, playerID, selected.Fingerprint)}
+		})
+	}
+
+	return m, nil
+}
+
+func (m Model) handleKeysResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.state != StateKeys {
+		return m, nil
+	}
+	m.loading = false
+
+	switch msg := msg.(type) {
+	case keysMsg:
+		if msg.err != nil {
+			m.err = linkError(msg.err)
+			return m, nil
+		}
+		m.keys.keys = msg.keys
+		if m.keys.cursor >= len(msg.keys) {
+			m.keys.cursor = len(msg.keys) - 1
+		}
+
+	case pairingCodeMsg:
+		if msg.err != nil {
+			m.err = linkError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		m.keys.code = msg.code
+		m.keys.codeExpires = msg.expires
+
+	case keyRevokedMsg:
+		if msg.err != nil {
+			m.err = linkError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		return m, m.loadKeys()
+	}
+	return m, nil
+}
+
+func (m Model) renderKeys() string {
+	title := TitleStyle.Render("🔑 Linked SSH Keys")
+
+	var rows []string
+	for i, k := range m.keys.keys {
+		lastUsed := "never"
+		if k.LastUsedAt.Valid {
+			lastUsed = k.LastUsedAt.Time.Format("2006-01-02")
+		}
+
+		label := truncateString(k.Fingerprint, 30)
+		if k.Fingerprint == m.fingerprint {
+			label += " (this session)"
+		}
+
+		row := fmt.Sprintf("%-46s added %s  used %s", label, k.CreatedAt.Format("2006-01-02"), lastUsed)
+		if i == m.keys.cursor {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+
+	if m.keys.code != "" {
+		rows = append(rows, "",
+			fmt.Sprintf("Pairing code: %s (valid until %s)",
+				StatValueStyle.Render(m.keys.code),
+				m.keys.codeExpires.Local().Format("15:04")),
+			"Connect with your other key and enter the code when asked for a username,",
+			"or run: ssh <host> link "+m.keys.code)
+	}
+
+	if m.loading {
+		rows = append(rows, "", renderLoading())
+	}
+
+	if m.err != nil {
+		rows = append(rows, "", ErrorStyle.Render(m.err.Error()))
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	footer := InstructionsStyle.Render("↑/↓: Select • G: Pairing code • X: Revoke • Esc: Back")
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
@@ -1,11 +1,16 @@
 package ui
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
+	"github.com/rayhanadev/2048/achievements"
 	"github.com/rayhanadev/2048/game"
 	"github.com/rayhanadev/2048/storage"
 )
@@ -17,6 +22,16 @@ const (
 	StatePlaying
 	StateGameOver
 	StateLeaderboard
+	StateStats
+	StateAchievements
+	StateRename
+	StateKeys
+	StateSettings
+	StateDeleteAccount
+	StateFriends
+	StateTeam
+	StateTournaments
+	StateAdmin
 )
 
 type AnimationState struct {
@@ -30,40 +45,75 @@ type AnimationState struct {
 }
 
 type Model struct {
-	state       AppState
-	game        *game.Game
-	player      *storage.Player
-	textInput   textinput.Model
-	leaderboard []storage.LeaderboardEntry
-	width       int
-	height      int
-	db          *storage.DB
-	fingerprint string
-	err         error
-	animation   AnimationState
+	ctx           context.Context
+	state         AppState
+	game          *game.Game
+	player        *storage.Player
+	textInput     textinput.Model
+	leaderboard   []storage.LeaderboardEntry
+	seasons       []storage.Season
+	seasonIndex   int
+	tab           leaderboardTab
+	teamStandings []storage.TeamStanding
+	teamScoring   storage.TeamScoring
+	headToHead    []storage.HeadToHead
+	stats         *storage.PlayerStats
+	achievements  *achievements.Tracker
+	unlocked      []storage.Achievement
+	toasts        []toast
+	rename        renameState
+	keys          keysState
+	settings      settingsState
+	friends       friendsState
+	team          teamState
+	tournaments   tournamentsState
+	run           *tournamentRun
+	admin         AdminTools
+	adminView     adminState
+	linking       bool
+	toastSeq      int
+	width         int
+	height        int
+	db            *storage.DB
+	fingerprint   string
+	err           error
+	loading       bool
+	animation     AnimationState
+
+	timeoutWarning   TimeoutWarningMsg
+	shutdownDeadline time.Time
+	ending           bool
+	// accountGone is set once a write finds the player deleted or merged away
+	accountGone  bool
+	broadcast    string
+	broadcastSeq int
+
+	guest        bool
+	claimCode    string
+	claimExpires time.Time
 }
 
 type tickMsg time.Time
 
-func NewModel(db *storage.DB, fingerprint string, player *storage.Player, initialState AppState) Model {
+// NewModel creates the model for one session. Database calls made by the
+// model are cancelled when ctx is done.
+func NewModel(ctx context.Context, db *storage.DB, fingerprint string, player *storage.Player, initialState AppState) Model {
 	ti := textinput.New()
 	ti.Placeholder = "Enter username"
 	ti.Focus()
 	ti.CharLimit = 20
 	ti.Width = 20
 
-	var bestScore int
-	if player != nil {
-		bestScore, _ = db.GetPlayerBestScore(player.ID)
-	}
-
 	m := Model{
-		state:       initialState,
-		game:        game.NewGame(bestScore),
-		player:      player,
-		textInput:   ti,
-		db:          db,
-		fingerprint: fingerprint,
+		ctx:          ctx,
+		state:        initialState,
+		game:         game.NewGame(0),
+		player:       player,
+		textInput:    ti,
+		achievements: newTracker(nil),
+		teamScoring:  storage.TeamScoringSum,
+		db:           db,
+		fingerprint:  fingerprint,
 	}
 
 	return m
@@ -79,7 +129,81 @@ func (m Model) Init() tea.Cmd {
 	if m.state == StateUsernameEntry {
 		return textinput.Blink
 	}
-	return nil
+	return m.loadPlayer()
+}
+
+// playerLoadedMsg carries what a returning player needs before their first game
+type playerLoadedMsg struct {
+	bestScore int
+	unlocked  []string
+	saved     *game.Snapshot
+	// savedState is the saved game as stored, to put back if it isn't resumed
+	savedState string
+	err        error
+}
+
+// accountMsg is the result of creating an account or linking a key to one
+type accountMsg struct {
+	player  *storage.Player
+	linking bool
+	err     error
+}
+
+// scoreSavedMsg reports that a finished game was stored
+type scoreSavedMsg struct {
+	game game.Game
+	err  error
+}
+
+func (m Model) loadPlayer() tea.Cmd {
+	if m.player == nil {
+		return nil
+	}
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		best, err := m.db.GetPlayerBestScore(ctx, playerID)
+		if err != nil {
+			return playerLoadedMsg{err: err}
+		}
+		unlocked, err := m.db.GetAchievements(ctx, playerID)
+		if err != nil {
+			return playerLoadedMsg{err: err}
+		}
+		msg := playerLoadedMsg{bestScore: best}
+		for _, a := range unlocked {
+			msg.unlocked = append(msg.unlocked, a.AchievementID)
+		}
+
+		// A saved game that can't be taken now stays saved for the next session
+		state, err := m.db.TakeSavedGame(ctx, playerID)
+		if err != nil {
+			return msg
+		}
+		msg.savedState = state
+		msg.saved = &game.Snapshot{}
+		if err := json.Unmarshal([]byte(state), msg.saved); err != nil {
+			msg.saved = nil
+		}
+		return msg
+	})
+}
+
+// saveScore stores a finished game, keeping a copy of it for the achievement
+// checks that run once the save is done
+func (m Model) saveScore() tea.Cmd {
+	if m.guest {
+		return m.saveGuestRun()
+	}
+	if m.player == nil {
+		return nil
+	}
+	playerID := m.player.ID
+	finished := *m.game
+	duration := m.game.Duration()
+	return m.query(func(ctx context.Context) tea.Msg {
+		err := m.db.SaveScore(ctx, playerID, finished.Score, finished.MaxTile(), finished.Moves, duration)
+		return scoreSavedMsg{game: finished, err: err}
+	})
 }
 
 func tickCmd() tea.Cmd {
@@ -89,15 +213,153 @@ func tickCmd() tea.Cmd {
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if playerGone(msg) && !m.ending && !m.settings.deleted {
+		return m.handleAccountGone()
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.timeoutWarning.Idle {
+			m.timeoutWarning = TimeoutWarningMsg{}
+		}
 		return m.handleKeyPress(msg)
 
+	case TimeoutWarningMsg:
+		m.timeoutWarning = msg
+		return m, nil
+
+	case ShutdownWarningMsg:
+		return m.handleShutdownWarning(msg)
+
+	case shutdownTickMsg:
+		if time.Now().Before(m.shutdownDeadline) {
+			return m, shutdownTick()
+		}
+		return m, nil
+
+	case SessionEndingMsg:
+		return m.handleSessionEnding()
+
+	case gameSuspendedMsg:
+		if msg.err != nil {
+			m.logger().Error("Failed to save game in progress", "error", msg.err)
+		}
+		return m, nil
+
+	case BroadcastMsg:
+		return m.showBroadcast(msg.Text)
+
+	case broadcastExpiredMsg:
+		if int(msg) == m.broadcastSeq {
+			m.broadcast = ""
+		}
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		return m, nil
 
+	case toastExpiredMsg:
+		m.dismissToast(int(msg))
+		return m, nil
+
+	case playerLoadedMsg:
+		if msg.err != nil {
+			m.logger().Error("Failed to load player", "error", msg.err)
+			return m, m.showToast("⚠️  Couldn't load your profile: " + dbError(msg.err).Error())
+		}
+		if msg.bestScore > m.game.BestScore {
+			m.game.BestScore = msg.bestScore
+		}
+		m.achievements = newTracker(msg.unlocked)
+
+		// Only resume into a game the player hasn't started playing yet
+		if msg.saved != nil && m.run == nil && m.game.Moves == 0 {
+			m.game = game.RestoreGame(*msg.saved, m.game.BestScore)
+			if m.state == StateGameOver {
+				m.state = StatePlaying
+			}
+			return m, m.showToast("▶️  Resumed your unfinished game")
+		}
+		if msg.saved != nil {
+			return m, m.returnSavedGame(msg.savedState)
+		}
+		return m, nil
+
+	case accountMsg:
+		return m.handleAccount(msg)
+
+	case scoreSavedMsg:
+		if msg.err != nil {
+			m.logger().Error("Failed to save score", "score", msg.game.Score, "error", msg.err)
+			return m, m.showToast("⚠️  Couldn't save your score: " + dbError(msg.err).Error())
+		}
+		return m, m.trackGameEnd(msg.game)
+
+	case guestRunSavedMsg:
+		return m.handleGuestRunSaved(msg)
+
+	case gameEndStatsMsg:
+		return m, tea.Batch(m.unlock(m.achievements.GameEnd(achievements.GameEndEvent{
+			Game:          &msg.game,
+			GamesPlayed:   msg.gamesPlayed,
+			CurrentStreak: msg.currentStreak,
+		}))...)
+
+	case achievementSavedMsg:
+		if msg.err != nil {
+			m.logger().Error("Failed to save achievement", "error", msg.err)
+			return m, m.showToast("⚠️  Couldn't save an achievement: " + dbError(msg.err).Error())
+		}
+		return m, nil
+
+	case leaderboardMsg:
+		return m.handleLeaderboardLoaded(msg)
+
+	case statsMsg:
+		if m.state != StateStats {
+			return m, nil
+		}
+		m.loading = false
+		m.stats, m.headToHead, m.err = msg.stats, msg.headToHead, nil
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+		}
+		return m, nil
+
+	case achievementsMsg:
+		if m.state != StateAchievements {
+			return m, nil
+		}
+		m.loading = false
+		m.unlocked, m.err = msg.unlocked, nil
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+		}
+		return m, nil
+
+	case renameInfoMsg, renameDoneMsg:
+		return m.handleRenameResult(msg)
+
+	case keysMsg, pairingCodeMsg, keyRevokedMsg:
+		return m.handleKeysResult(msg)
+
+	case hiddenSetMsg, accountDeletedMsg:
+		return m.handleSettingsResult(msg)
+
+	case friendsMsg, followedMsg, unfollowedMsg:
+		return m.handleFriendsResult(msg)
+
+	case teamMsg, teamChangedMsg:
+		return m.handleTeamResult(msg)
+
+	case tournamentsMsg, tournamentMsg, tournamentRefreshMsg, attemptStartedMsg, attemptFinishedMsg:
+		return m.handleTournamentsResult(msg)
+
+	case adminPlayersMsg, adminPlayerMsg, adminSessionsMsg, adminDoneMsg:
+		return m.handleAdminResult(msg)
+
 	case tickMsg:
 		if m.animation.Active {
 			m.animation.Frame++
@@ -111,7 +373,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	if m.state == StateUsernameEntry {
+	if m.typing() {
 		var cmd tea.Cmd
 		m.textInput, cmd = m.textInput.Update(msg)
 		return m, cmd
@@ -121,9 +383,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.accountGone {
+		return m, tea.Quit
+	}
+
 	switch msg.String() {
-	case "ctrl+c", "q":
+	case "ctrl+c":
 		return m, tea.Quit
+	case "q":
+		// Let text fields receive the letter q
+		if !m.typing() {
+			return m, tea.Quit
+		}
 	}
 
 	switch m.state {
@@ -135,29 +406,80 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleGameOverInput(msg)
 	case StateLeaderboard:
 		return m.handleLeaderboardInput(msg)
+	case StateStats:
+		return m.handleStatsInput(msg)
+	case StateAchievements:
+		return m.handleAchievementsInput(msg)
+	case StateRename:
+		return m.handleRenameInput(msg)
+	case StateKeys:
+		return m.handleKeysInput(msg)
+	case StateSettings:
+		return m.handleSettingsInput(msg)
+	case StateDeleteAccount:
+		return m.handleDeleteAccountInput(msg)
+	case StateFriends:
+		return m.handleFriendsInput(msg)
+	case StateTeam:
+		return m.handleTeamInput(msg)
+	case StateTournaments:
+		return m.handleTournamentsInput(msg)
+	case StateAdmin:
+		return m.handleAdminInput(msg)
 	}
 
 	return m, nil
 }
 
+// typing reports whether key presses go to the text input
+func (m Model) typing() bool {
+	switch m.state {
+	case StateUsernameEntry, StateRename, StateDeleteAccount:
+		return true
+	case StateFriends:
+		return m.friends.adding
+	case StateTeam:
+		return m.team.creating
+	case StateAdmin:
+		return m.adminView.input != adminInputNone
+	}
+	return false
+}
+
 func (m Model) handleUsernameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
-	case tea.KeyEnter:
-		username := m.textInput.Value()
-		if len(username) < 3 {
-			return m, nil
+	case tea.KeyTab:
+		// Switch between creating an account and linking this key to one
+		m.linking = !m.linking
+		m.err = nil
+		m.textInput.SetValue("")
+		if m.linking {
+			m.textInput.Placeholder = "Enter pairing code"
+		} else {
+			m.textInput.Placeholder = "Enter username"
 		}
+		return m, nil
 
-		player, err := m.db.CreatePlayer(m.fingerprint, username)
-		if err != nil {
-			m.err = err
+	case tea.KeyEnter:
+		if m.loading {
 			return m, nil
 		}
-
-		m.player = player
-		m.state = StatePlaying
-		m.game = game.NewGame(0)
-		return m, nil
+		m.loading = true
+		m.err = nil
+
+		linking := m.linking
+		value := strings.TrimSpace(m.textInput.Value())
+		fingerprint := m.fingerprint
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			var player *storage.Player
+			var err error
+			if linking {
+				player, err = m.db.LinkKey(ctx, value, fingerprint)
+			} else {
+				player, err = m.db.CreatePlayer(ctx, fingerprint, value)
+			}
+			return accountMsg{player: player, linking: linking, err: err}
+		})
 	}
 
 	var cmd tea.Cmd
@@ -165,6 +487,25 @@ func (m Model) handleUsernameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m Model) handleAccount(msg accountMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		if msg.linking {
+			m.err = linkError(msg.err)
+		} else {
+			m.err = usernameError(msg.err)
+		}
+		return m, nil
+	}
+	m.err = nil
+
+	m.player = msg.player
+	m.state = StatePlaying
+	m.game = game.NewGame(0)
+	m.achievements = newTracker(nil)
+	return m, m.loadPlayer()
+}
+
 func (m Model) handleGameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.animation.Active {
 		return m, nil
@@ -187,21 +528,46 @@ func (m Model) handleGameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		dir = game.Right
 		moved = true
 	case "r":
+		if m.run != nil {
+			return m.leaveTournamentRun()
+		}
 		m.game.Reset()
+		m.achievements.NewGame()
 		return m, nil
 	case "b":
-		entries, err := m.db.GetLeaderboard(10)
-		if err == nil {
-			m.leaderboard = entries
-		}
-		m.state = StateLeaderboard
-		return m, nil
+		return m.openLeaderboard()
+	case "t":
+		return m.openStats()
+	case "c":
+		return m.openAchievements()
+	case "n":
+		return m.openRename()
+	case "u":
+		return m.openKeys()
+	case "o":
+		return m.openSettings()
+	case "f":
+		return m.openFriends()
+	case "m":
+		return m.openTeam()
+	case "p":
+		return m.openTournaments()
+	case "A":
+		return m.openAdmin()
 	}
 
 	if moved {
 		result := m.game.Move(dir)
 		if result != nil && result.Moved {
+			m.recordMove()
 			shouldAnimate := dir == game.Down || dir == game.Right
+			var cmds []tea.Cmd
+
+			cmds = append(cmds, m.unlock(m.achievements.Move(achievements.MoveEvent{
+				Direction: dir,
+				Result:    result,
+				Game:      m.game,
+			}))...)
 
 			if shouldAnimate {
 				m.animation = AnimationState{
@@ -216,15 +582,19 @@ func (m Model) handleGameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 
 			if m.game.GameOver {
-				if m.player != nil {
-					m.db.SaveScore(m.player.ID, m.game.Score, m.game.MaxTile())
+				m.recordGameEnd()
+				if m.run != nil {
+					cmds = append(cmds, m.finishAttempt())
+				} else {
+					cmds = append(cmds, m.saveScore())
 				}
 				m.state = StateGameOver
 			}
 
 			if shouldAnimate {
-				return m, tickCmd()
+				cmds = append(cmds, tickCmd())
 			}
+			return m, tea.Batch(cmds...)
 		}
 	}
 
@@ -234,34 +604,210 @@ func (m Model) handleGameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleGameOverInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "r":
+		if m.run != nil {
+			return m.leaveTournamentRun()
+		}
+		m.claimCode = ""
 		m.game.Reset()
+		m.achievements.NewGame()
 		m.state = StatePlaying
 		return m, nil
 	case "b":
-		entries, err := m.db.GetLeaderboard(10)
-		if err == nil {
-			m.leaderboard = entries
+		return m.openLeaderboard()
+	case "t":
+		return m.openStats()
+	case "c":
+		return m.openAchievements()
+	case "n":
+		return m.openRename()
+	case "u":
+		return m.openKeys()
+	case "o":
+		return m.openSettings()
+	case "f":
+		return m.openFriends()
+	case "m":
+		return m.openTeam()
+	case "p":
+		return m.openTournaments()
+	case "A":
+		return m.openAdmin()
+	}
+	return m, nil
+}
+
+// leaderboardTab selects whose scores the leaderboard ranks
+type leaderboardTab int
+
+const (
+	tabGlobal leaderboardTab = iota
+	tabFriends
+	tabTeams
+	tabCount
+)
+
+// leaderboardMsg carries a leaderboard, and the season list when it was reloaded
+type leaderboardMsg struct {
+	seasons     []storage.Season
+	seasonIndex int
+	tab         leaderboardTab
+	scoring     storage.TeamScoring
+	entries     []storage.LeaderboardEntry
+	teams       []storage.TeamStanding
+	err         error
+}
+
+// leaderboardSize is how many entries the leaderboard screen lists
+const leaderboardSize = 10
+
+// openLeaderboard shows the current season's leaderboard, or the all-time
+// one when no season is running
+func (m Model) openLeaderboard() (tea.Model, tea.Cmd) {
+	m.seasons = nil
+	m.seasonIndex = 0
+	m.tab = tabGlobal
+	m.leaderboard = nil
+	m.teamStandings = nil
+	m.loading = true
+	m.err = nil
+	m.state = StateLeaderboard
+
+	return m, m.query(func(ctx context.Context) tea.Msg {
+		seasons, err := m.db.GetSeasons(ctx)
+		if err != nil {
+			return leaderboardMsg{err: err}
+		}
+
+		msg := leaderboardMsg{seasons: seasons}
+		var season *storage.Season
+		if len(seasons) > 0 && seasons[0].Active(time.Now()) {
+			msg.seasonIndex = 1
+			season = &seasons[0]
+		}
+		msg.entries, msg.err = m.db.GetLeaderboard(ctx, season, leaderboardSize)
+		return msg
+	})
+}
+
+// selectedSeason returns the season being viewed, or nil for all time
+func (m Model) selectedSeason() *storage.Season {
+	if m.seasonIndex == 0 {
+		return nil
+	}
+	return &m.seasons[m.seasonIndex-1]
+}
+
+func (m Model) loadLeaderboard() (tea.Model, tea.Cmd) {
+	m.leaderboard = nil
+	m.teamStandings = nil
+	m.loading = true
+	m.err = nil
+
+	season := m.selectedSeason()
+	msg := leaderboardMsg{seasonIndex: m.seasonIndex, tab: m.tab, scoring: m.teamScoring}
+	player := m.player
+	return m, m.query(func(ctx context.Context) tea.Msg {
+		switch msg.tab {
+		case tabFriends:
+			msg.entries, msg.err = m.db.GetFriendsLeaderboard(ctx, player.ID, season, leaderboardSize)
+		case tabTeams:
+			msg.teams, msg.err = m.db.GetTeamLeaderboard(ctx, season, msg.scoring, leaderboardSize)
+		default:
+			msg.entries, msg.err = m.db.GetLeaderboard(ctx, season, leaderboardSize)
 		}
-		m.state = StateLeaderboard
+		return msg
+	})
+}
+
+func (m Model) handleLeaderboardLoaded(msg leaderboardMsg) (tea.Model, tea.Cmd) {
+	if m.state != StateLeaderboard {
 		return m, nil
 	}
+	if msg.seasons != nil {
+		m.seasons = msg.seasons
+		m.seasonIndex = msg.seasonIndex
+	} else if msg.seasonIndex != m.seasonIndex || msg.tab != m.tab || msg.scoring != m.teamScoring {
+		// The player has already moved on to another season or tab
+		return m, nil
+	}
+
+	m.loading = false
+	m.leaderboard = msg.entries
+	m.teamStandings = msg.teams
+	m.err = nil
+	if msg.err != nil {
+		m.err = dbError(msg.err)
+	}
 	return m, nil
 }
 
 func (m Model) handleLeaderboardInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
-	case "escape", "b", "enter", " ":
+	case "esc", "b", "enter", " ":
+		m.loading = false
 		if m.game.GameOver {
 			m.state = StateGameOver
 		} else {
 			m.state = StatePlaying
 		}
 		return m, nil
+	case "right", "l", "]":
+		// Seasons are listed newest first, so moving right goes back in time
+		if m.seasonIndex < len(m.seasons) {
+			m.seasonIndex++
+			return m.loadLeaderboard()
+		}
+		return m, nil
+	case "left", "h", "[":
+		if m.seasonIndex > 0 {
+			m.seasonIndex--
+			return m.loadLeaderboard()
+		}
+		return m, nil
+	case "tab", "shift+tab":
+		if m.player == nil {
+			return m, nil
+		}
+		step := leaderboardTab(1)
+		if msg.String() == "shift+tab" {
+			step = tabCount - 1
+		}
+		m.tab = (m.tab + step) % tabCount
+		return m.loadLeaderboard()
+	case "s":
+		if m.tab != tabTeams {
+			return m, nil
+		}
+		if m.teamScoring == storage.TeamScoringSum {
+			m.teamScoring = storage.TeamScoringAverage
+		} else {
+			m.teamScoring = storage.TeamScoringSum
+		}
+		return m.loadLeaderboard()
 	}
 	return m, nil
 }
 
 func (m Model) View() string {
+	if m.accountGone {
+		return m.renderAccountGone()
+	}
+
+	view := m.renderState()
+	if broadcast := m.renderBroadcast(); broadcast != "" {
+		view = lipgloss.JoinVertical(lipgloss.Center, broadcast, view)
+	}
+	if warning := m.renderShutdownWarning(); warning != "" {
+		return lipgloss.JoinVertical(lipgloss.Center, warning, view)
+	}
+	if warning := m.renderTimeoutWarning(); warning != "" {
+		return lipgloss.JoinVertical(lipgloss.Center, warning, view)
+	}
+	return view
+}
+
+// renderState renders the screen for the current state
+func (m Model) renderState() string {
 	switch m.state {
 	case StateUsernameEntry:
 		return m.renderUsernameEntry()
@@ -271,6 +817,26 @@ func (m Model) View() string {
 		return m.renderGameOver()
 	case StateLeaderboard:
 		return m.renderLeaderboard()
+	case StateStats:
+		return m.renderStats()
+	case StateAchievements:
+		return m.renderAchievements()
+	case StateRename:
+		return m.renderRename()
+	case StateKeys:
+		return m.renderKeys()
+	case StateSettings:
+		return m.renderSettings()
+	case StateDeleteAccount:
+		return m.renderDeleteAccount()
+	case StateFriends:
+		return m.renderFriends()
+	case StateTeam:
+		return m.renderTeam()
+	case StateTournaments:
+		return m.renderTournaments()
+	case StateAdmin:
+		return m.renderAdmin()
 	}
 	return ""
 }
@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+// renameHistoryCount is how many past usernames the rename screen lists
+const renameHistoryCount = 5
+
+// usernameError turns storage errors into messages suitable for players
+func usernameError(err error) error {
+	var invalid *storage.UsernameError
+	if errors.As(err, &invalid) ||
+		errors.Is(err, storage.ErrUsernameTaken) ||
+		errors.Is(err, storage.ErrRenameCooldown) {
+		return err
+	}
+	return dbError(err)
+}
+
+type renameState struct {
+	history  []storage.UsernameChange
+	nextAt   time.Time
+	returnTo AppState
+}
+
+// renameInfoMsg carries the rename screen's history and cooldown
+type renameInfoMsg struct {
+	history []storage.UsernameChange
+	nextAt  time.Time
+	err     error
+}
+
+// renameDoneMsg is the result of saving a new username
+type renameDoneMsg struct {
+	username string
+	err      error
+}
+
+func (m Model) openRename() (tea.Model, tea.Cmd) {
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.rename = renameState{returnTo: m.state}
+	m.err = nil
+	m.loading = true
+	m.textInput.SetValue(m.player.Username)
+	m.textInput.CursorEnd()
+	m.state = StateRename
+
+	playerID := m.player.ID
+	return m, tea.Batch(textinput.Blink, m.query(func(ctx context.Context) tea.Msg {
+		history, err := m.db.GetUsernameHistory(ctx, playerID, renameHistoryCount)
+		if err != nil {
+			return renameInfoMsg{err: err}
+		}
+		next, err := m.db.NextRenameAt(ctx, playerID)
+		return renameInfoMsg{history: history, nextAt: next, err: err}
+	}))
+}
+
+func (m Model) handleRenameInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.err = nil
+		m.loading = false
+		m.state = m.rename.returnTo
+		return m, nil
+
+	case tea.KeyEnter:
+		if m.loading {
+			return m, nil
+		}
+
+		username := strings.TrimSpace(m.textInput.Value())
+		if username == m.player.Username {
+			m.state = m.rename.returnTo
+			return m, nil
+		}
+
+		m.loading = true
+		m.err = nil
+		playerID := m.player.ID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			return renameDoneMsg{username: username, err: m.db.UpdateUsername(ctx, playerID, username)}
+		})
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleRenameResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case renameInfoMsg:
+		if m.state != StateRename {
+			return m, nil
+		}
+		m.loading = false
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.rename.history = msg.history
+		m.rename.nextAt = msg.nextAt
+
+	case renameDoneMsg:
+		if msg.err == nil {
+			m.player.Username = msg.username
+		}
+		if m.state != StateRename {
+			return m, nil
+		}
+		m.loading = false
+		if msg.err != nil {
+			m.err = usernameError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		m.state = m.rename.returnTo
+	}
+	return m, nil
+}
+
+func (m Model) renderRename() string {
+	title := TitleStyle.Render("✏️  Change Username")
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Current username: %s\n\n", m.player.Username))
+
+	if !m.rename.nextAt.IsZero() {
+		content.WriteString(fmt.Sprintf("You can rename again after %s\n\n", m.rename.nextAt.Local().Format("2006-01-02 15:04")))
+	}
+
+	content.WriteString(m.textInput.View())
+
+	if m.loading {
+		content.WriteString("\n\n")
+		content.WriteString(renderLoading())
+	}
+
+	if m.err != nil {
+		content.WriteString("\n\n")
+		content.WriteString(ErrorStyle.Render(m.err.Error()))
+	}
+
+	if len(m.rename.history) > 0 {
+		content.WriteString("\n\n")
+		content.WriteString(StatLabelStyle.Render("Previous names"))
+		for _, c := range m.rename.history {
+			content.WriteString(fmt.Sprintf("\n%s  %s → %s",
+				c.ChangedAt.Format("2006-01-02"),
+				c.OldUsername,
+				c.NewUsername))
+		}
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content.String())
+
+	footer := InstructionsStyle.Render("Press Enter to save • Esc to cancel")
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+// TimeoutWarningMsg warns the player that the server will disconnect them at
+// Deadline. Idle warnings end as soon as the player presses a key. A zero
+// Deadline clears the warning.
+type TimeoutWarningMsg struct {
+	Deadline time.Time
+	Idle     bool
+}
+
+// ShutdownWarningMsg warns the player that the server is shutting down and
+// will end their session at Deadline
+type ShutdownWarningMsg struct {
+	Deadline time.Time
+}
+
+// shutdownTickMsg refreshes the shutdown countdown
+type shutdownTickMsg struct{}
+
+// SessionEndingMsg asks the model to save the game in progress and quit
+// because the server is about to close the session
+type SessionEndingMsg struct{}
+
+// gameSuspendedMsg reports whether the game in progress was saved before quitting
+type gameSuspendedMsg struct {
+	err error
+}
+
+// suspendGame saves the game in progress so it can be resumed on the next
+// connection. Tournament attempts can't be resumed, so they are scored instead.
+func (m Model) suspendGame() tea.Cmd {
+	if m.player == nil || m.accountGone || m.game.GameOver || m.game.Moves == 0 {
+		return nil
+	}
+	if m.run != nil {
+		return m.finishAttempt()
+	}
+
+	playerID := m.player.ID
+	snapshot := m.game.Snapshot()
+	return m.query(func(ctx context.Context) tea.Msg {
+		state, err := json.Marshal(snapshot)
+		if err != nil {
+			return gameSuspendedMsg{err: err}
+		}
+		return gameSuspendedMsg{err: m.db.SaveGame(ctx, playerID, string(state))}
+	})
+}
+
+// returnSavedGame puts back a saved game that was taken when the player
+// loaded but not resumed, because they had already started another one
+func (m Model) returnSavedGame(state string) tea.Cmd {
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		return gameSuspendedMsg{err: m.db.ReturnSavedGame(ctx, playerID, state)}
+	})
+}
+
+// playerGone reports whether msg is the result of a write for a player who
+// no longer exists, because they were deleted or merged into another player
+// while this session was open
+func playerGone(msg tea.Msg) bool {
+	var err error
+	switch msg := msg.(type) {
+	case scoreSavedMsg:
+		err = msg.err
+	case gameSuspendedMsg:
+		err = msg.err
+	case achievementSavedMsg:
+		err = msg.err
+	case hiddenSetMsg:
+		err = msg.err
+	case renameDoneMsg:
+		err = msg.err
+	case pairingCodeMsg:
+		err = msg.err
+	case keyRevokedMsg:
+		err = msg.err
+	case teamChangedMsg:
+		err = msg.err
+	case attemptStartedMsg:
+		err = msg.err
+	case attemptFinishedMsg:
+		err = msg.err
+	}
+	return errors.Is(err, storage.ErrPlayerNotFound)
+}
+
+// handleAccountGone stops the session from playing on as a player who no
+// longer exists. The next key press ends it.
+func (m Model) handleAccountGone() (tea.Model, tea.Cmd) {
+	m.logger().Warn("Player no longer exists, ending session")
+	m.accountGone = true
+	return m, nil
+}
+
+func (m Model) renderAccountGone() string {
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render("This account no longer exists. It was deleted or merged\ninto another account while you were connected.")
+	footer := InstructionsStyle.Render("Press any key to exit")
+	return lipgloss.JoinVertical(lipgloss.Center, TitleStyle.Render("👋 Goodbye"), box, footer)
+}
+
+func (m Model) handleShutdownWarning(msg ShutdownWarningMsg) (tea.Model, tea.Cmd) {
+	m.shutdownDeadline = msg.Deadline
+	return m, shutdownTick()
+}
+
+// shutdownTick redraws the countdown every second until the deadline passes
+func shutdownTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return shutdownTickMsg{}
+	})
+}
+
+func (m Model) handleSessionEnding() (tea.Model, tea.Cmd) {
+	if m.ending {
+		return m, nil
+	}
+	m.ending = true
+
+	save := m.suspendGame()
+	if save == nil {
+		return m, tea.Quit
+	}
+	return m, tea.Sequence(save, tea.Quit)
+}
+
+// renderShutdownWarning shows how long is left before the server shuts down
+func (m Model) renderShutdownWarning() string {
+	if m.shutdownDeadline.IsZero() {
+		return ""
+	}
+
+	left := max(time.Until(m.shutdownDeadline).Round(time.Second), 0)
+	text := fmt.Sprintf("🔄 Server restarting in %s", left)
+	if m.player != nil {
+		text += " • Your game will be saved"
+	}
+	return BannerStyle.Render(text)
+}
+
+// renderTimeoutWarning shows how long is left before the server disconnects the player
+func (m Model) renderTimeoutWarning() string {
+	if m.timeoutWarning.Deadline.IsZero() {
+		return ""
+	}
+
+	left := max(time.Until(m.timeoutWarning.Deadline).Round(time.Second), 0)
+	if m.timeoutWarning.Idle {
+		return BannerStyle.Render(fmt.Sprintf("⏳ Disconnecting in %s for inactivity • Press any key to stay", left))
+	}
+	return BannerStyle.Render(fmt.Sprintf("⏳ Session time limit reached • Disconnecting in %s", left))
+}
@@ -0,0 +1,252 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Settings screen rows
+const (
+	settingVisibility = iota
+	settingExport
+	settingDelete
+	settingCount
+)
+
+// hiddenSetMsg reports whether the leaderboard visibility change was stored
+type hiddenSetMsg struct {
+	hidden bool
+	err    error
+}
+
+// accountDeletedMsg reports whether the account was deleted
+type accountDeletedMsg struct {
+	err error
+}
+
+type settingsState struct {
+	cursor     int
+	showExport bool
+	deleted    bool
+	returnTo   AppState
+}
+
+func (m Model) openSettings() (tea.Model, tea.Cmd) {
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.settings = settingsState{returnTo: m.state}
+	m.err = nil
+	m.loading = false
+	m.state = StateSettings
+	return m, nil
+}
+
+func (m Model) handleSettingsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "o":
+		m.err = nil
+		m.loading = false
+		m.state = m.settings.returnTo
+		return m, nil
+
+	case "up", "k":
+		if m.settings.cursor > 0 {
+			m.settings.cursor--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.settings.cursor < settingCount-1 {
+			m.settings.cursor++
+		}
+		return m, nil
+
+	case "enter", " ":
+		switch m.settings.cursor {
+		case settingVisibility:
+			if m.loading {
+				return m, nil
+			}
+			m.loading = true
+			playerID, hidden := m.player.ID, !m.player.Hidden
+			return m, m.query(func(ctx context.Context) tea.Msg {
+				return hiddenSetMsg{hidden: hidden, err: m.db.SetHidden(ctx, playerID, hidden)}
+			})
+		case settingExport:
+			m.settings.showExport = !m.settings.showExport
+		case settingDelete:
+			m.err = nil
+			m.textInput.SetValue("")
+			m.textInput.Placeholder = m.player.Username
+			m.state = StateDeleteAccount
+			return m, textinput.Blink
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m Model) handleDeleteAccountInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.settings.deleted {
+		return m, tea.Quit
+	}
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.err = nil
+		m.textInput.Placeholder = "Enter username"
+		m.state = StateSettings
+		return m, nil
+
+	case tea.KeyEnter:
+		if !strings.EqualFold(strings.TrimSpace(m.textInput.Value()), m.player.Username) {
+			m.err = errors.New("that doesn't match your username")
+			return m, nil
+		}
+
+		m.loading = true
+		m.err = nil
+		playerID := m.player.ID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			_, err := m.db.DeletePlayer(ctx, playerID, fmt.Sprintf("player:%d", playerID))
+			return accountDeletedMsg{err: err}
+		})
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleSettingsResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case hiddenSetMsg:
+		// The change applies even if the player has left the screen
+		if msg.err == nil {
+			m.player.Hidden = msg.hidden
+		}
+		if m.state != StateSettings {
+			return m, nil
+		}
+		m.loading = false
+		m.err = nil
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+		}
+
+	case accountDeletedMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		m.settings.deleted = true
+	}
+	return m, nil
+}
+
+func (m Model) renderSettings() string {
+	title := TitleStyle.Render("⚙️  Settings")
+
+	visibility := "Shown"
+	if m.player.Hidden {
+		visibility = "Hidden"
+	}
+
+	items := []string{
+		fmt.Sprintf("Leaderboard visibility: %s", StatValueStyle.Render(visibility)),
+		"Export my data",
+		"Delete my account",
+	}
+
+	var rows []string
+	for i, item := range items {
+		if i == m.settings.cursor {
+			rows = append(rows, LeaderboardHighlightStyle.Render("▸ ")+item)
+		} else {
+			rows = append(rows, "  "+item)
+		}
+	}
+
+	if m.player.Hidden {
+		rows = append(rows, "", "Your scores are kept but left off the public leaderboard.")
+	}
+
+	if m.settings.showExport {
+		rows = append(rows, "",
+			"Download everything stored about you as JSON:",
+			StatValueStyle.Render("ssh <host> export-data > 2048-data.json"))
+	}
+
+	if m.loading {
+		rows = append(rows, "", renderLoading())
+	}
+
+	if m.err != nil {
+		rows = append(rows, "", ErrorStyle.Render(m.err.Error()))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(strings.Join(rows, "\n"))
+
+	footer := InstructionsStyle.Render("↑/↓: Select • Enter: Choose • Esc: Back")
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
+
+func (m Model) renderDeleteAccount() string {
+	if m.settings.deleted {
+		box := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("#3d3d5c")).
+			Padding(1, 2).
+			Render("Your account and all of its data have been deleted.\nThanks for playing!")
+		footer := InstructionsStyle.Render("Press any key to exit")
+		return lipgloss.JoinVertical(lipgloss.Center, TitleStyle.Render("👋 Goodbye"), box, footer)
+	}
+
+	title := TitleStyle.Render("🗑️  Delete Account")
+
+	var content strings.Builder
+	content.WriteString("This permanently deletes your account, scores,\n")
+	content.WriteString("achievements, username history and linked keys.\n")
+	content.WriteString("It can't be undone.\n\n")
+	content.WriteString(fmt.Sprintf("Type %s to confirm:\n\n", StatValueStyle.Render(m.player.Username)))
+	content.WriteString(m.textInput.View())
+
+	if m.loading {
+		content.WriteString("\n\n")
+		content.WriteString(StatLabelStyle.Render("Deleting…"))
+	}
+
+	if m.err != nil {
+		content.WriteString("\n\n")
+		content.WriteString(ErrorStyle.Render(m.err.Error()))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content.String())
+
+	footer := InstructionsStyle.Render("Press Enter to delete • Esc to cancel")
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+// recentScoreCount is how many games the sparkline covers
+const recentScoreCount = 30
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// statsMsg carries the stats screen's data
+type statsMsg struct {
+	stats      *storage.PlayerStats
+	headToHead []storage.HeadToHead
+	err        error
+}
+
+func (m Model) openStats() (tea.Model, tea.Cmd) {
+	m.stats = nil
+	m.headToHead = nil
+	m.err = nil
+	m.loading = false
+	m.state = StateStats
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.loading = true
+	playerID := m.player.ID
+	return m, m.query(func(ctx context.Context) tea.Msg {
+		stats, err := m.db.GetPlayerStats(ctx, playerID, recentScoreCount)
+		if err != nil {
+			return statsMsg{err: err}
+		}
+		headToHead, err := m.db.GetHeadToHead(ctx, playerID)
+		return statsMsg{stats: stats, headToHead: headToHead, err: err}
+	})
+}
+
+func (m Model) handleStatsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "t", "enter", " ":
+		m.loading = false
+		if m.game.GameOver {
+			m.state = StateGameOver
+		} else {
+			m.state = StatePlaying
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m Model) renderStats() string {
+	title := TitleStyle.Render("📊 Player Statistics 📊")
+
+	var rows []string
+	if m.loading {
+		rows = append(rows, renderLoading())
+	} else if m.err != nil {
+		rows = append(rows, ErrorStyle.Render(m.err.Error()))
+	} else if m.stats == nil || m.stats.GamesPlayed == 0 {
+		rows = append(rows, "No finished games yet!")
+	} else {
+		s := m.stats
+		rows = append(rows,
+			statRow("Games played", fmt.Sprintf("%d", s.GamesPlayed)),
+			statRow("Average score", fmt.Sprintf("%.0f", s.AverageScore)),
+			statRow("Median score", fmt.Sprintf("%.0f", s.MedianScore)),
+			statRow("Best score", fmt.Sprintf("%d", s.BestScore)),
+			statRow("Highest tile", fmt.Sprintf("%d", s.HighestTile)),
+			"",
+		)
+
+		for _, tc := range s.TileCounts {
+			// Always list the milestones up to the winning tile
+			if tc.Tile > 2048 && tc.Games == 0 {
+				break
+			}
+			rows = append(rows, statRow(fmt.Sprintf("Reached %d", tc.Tile), fmt.Sprintf("%d×", tc.Games)))
+		}
+
+		rows = append(rows,
+			"",
+			statRow("Total moves", fmt.Sprintf("%d", s.TotalMoves)),
+			statRow("Play time", formatDuration(s.TotalPlayTime)),
+			statRow("Current streak", pluralDays(s.CurrentStreak)),
+			statRow("Longest streak", pluralDays(s.LongestStreak)),
+			"",
+			StatLabelStyle.Render("Recent scores"),
+			SparklineStyle.Render(sparkline(s.RecentScores)),
+		)
+	}
+
+	if len(m.headToHead) > 0 && !m.loading && m.err == nil {
+		rows = append(rows, "", StatLabelStyle.Render("Head to head"))
+		rows = append(rows, m.renderHeadToHead()...)
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	footer := InstructionsStyle.Render("Press Enter or T to return")
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
+
+// renderHeadToHead lists each followed player's best score and tile next to
+// the player's own, and the days each of them came out on top
+func (m Model) renderHeadToHead() []string {
+	var best, tile int
+	if m.stats != nil {
+		best, tile = m.stats.BestScore, m.stats.HighestTile
+	}
+
+	rows := []string{fmt.Sprintf("%-15s %15s %11s %9s", "", "Best", "Tile", "Days W–L")}
+	for _, h := range m.headToHead {
+		rows = append(rows, fmt.Sprintf("%-15s %15s %11s %9s",
+			truncateString(h.Username, 15),
+			fmt.Sprintf("%d vs %d", best, h.BestScore),
+			fmt.Sprintf("%d vs %d", tile, h.HighestTile),
+			fmt.Sprintf("%d–%d", h.Wins, h.Losses)))
+	}
+	return rows
+}
+
+func statRow(label, value string) string {
+	return StatLabelStyle.Render(label) + StatValueStyle.Render(value)
+}
+
+// sparkline draws one block character per score, scaled to the highest score
+func sparkline(scores []int) string {
+	if len(scores) == 0 {
+		return ""
+	}
+
+	max := 0
+	for _, s := range scores {
+		if s > max {
+			max = s
+		}
+	}
+
+	var b strings.Builder
+	for _, s := range scores {
+		idx := 0
+		if max > 0 {
+			idx = s * (len(sparkBlocks) - 1) / max
+		}
+		b.WriteRune(sparkBlocks[idx])
+	}
+	return b.String()
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h := int(d.Hours())
+	mins := int(d.Minutes()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%dh %dm", h, mins)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
+
+func pluralDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
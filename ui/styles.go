@@ -64,6 +64,7 @@ var (
 
 	InstructionsStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("#776e65")).
+				Align(lipgloss.Center).
 				MarginTop(1)
 
 	GameOverStyle = lipgloss.NewStyle().
@@ -115,6 +116,35 @@ var (
 					Bold(true).
 					Foreground(lipgloss.Color("#edc22e")).
 					Padding(0, 1)
+
+	StatLabelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#bbada0")).
+			Width(16)
+
+	StatValueStyle = lipgloss.NewStyle().
+			Foreground(LightText).
+			Bold(true)
+
+	SparklineStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#edc22e"))
+
+	ToastStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#776e65")).
+			Background(lipgloss.Color("#edc22e")).
+			Padding(0, 2)
+
+	AchievementLockedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#5a5a5a"))
+
+	ErrorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#ff0000"))
+
+	BannerStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("#f9f6f2")).
+			Background(lipgloss.Color("#f65e3b")).
+			Padding(0, 2)
 )
 
 // GetTileStyle returns the style for a specific tile value
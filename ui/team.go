@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
+)
+
+type teamState struct {
+	team     *storage.Team
+	members  []storage.TeamMember
+	teams    []storage.Team
+	cursor   int
+	creating bool
+	returnTo AppState
+}
+
+// teamError turns storage errors from team changes into messages suitable for players
+func teamError(err error) error {
+	var invalid *storage.TeamNameError
+	if errors.As(err, &invalid) ||
+		errors.Is(err, storage.ErrTeamNotFound) ||
+		errors.Is(err, storage.ErrTeamNameTaken) ||
+		errors.Is(err, storage.ErrAlreadyInTeam) ||
+		errors.Is(err, storage.ErrNotInTeam) {
+		return err
+	}
+	return dbError(err)
+}
+
+// teamMsg carries the player's team and its members, or every team to
+// choose from when the player has none
+type teamMsg struct {
+	team    *storage.Team
+	members []storage.TeamMember
+	teams   []storage.Team
+	err     error
+}
+
+// teamChangedMsg reports whether creating, joining or leaving a team worked
+type teamChangedMsg struct {
+	err error
+}
+
+func (m Model) openTeam() (tea.Model, tea.Cmd) {
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.team = teamState{returnTo: m.state}
+	m.err = nil
+	m.state = StateTeam
+	return m, m.loadTeam()
+}
+
+func (m *Model) loadTeam() tea.Cmd {
+	m.loading = true
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		team, err := m.db.GetPlayerTeam(ctx, playerID)
+		if errors.Is(err, storage.ErrNotInTeam) {
+			teams, err := m.db.GetTeams(ctx)
+			return teamMsg{teams: teams, err: err}
+		}
+		if err != nil {
+			return teamMsg{err: err}
+		}
+		members, err := m.db.GetTeamMembers(ctx, team.ID)
+		return teamMsg{team: team, members: members, err: err}
+	})
+}
+
+// changeTeam runs a team change, then reloads the screen
+func (m *Model) changeTeam(fn func(ctx context.Context, playerID int64) error) tea.Cmd {
+	m.loading = true
+	m.err = nil
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		return teamChangedMsg{err: fn(ctx, playerID)}
+	})
+}
+
+func (m Model) handleTeamInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.team.creating {
+		return m.handleCreateTeamInput(msg)
+	}
+
+	switch msg.String() {
+	case "esc", "m":
+		m.err = nil
+		m.loading = false
+		m.state = m.team.returnTo
+		return m, nil
+
+	case "up", "k":
+		if m.team.cursor > 0 {
+			m.team.cursor--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.team.cursor < len(m.team.teams)-1 {
+			m.team.cursor++
+		}
+		return m, nil
+	}
+
+	if m.loading {
+		return m, nil
+	}
+
+	if m.team.team != nil {
+		if msg.String() == "x" {
+			return m, m.changeTeam(func(ctx context.Context, playerID int64) error {
+				_, err := m.db.LeaveTeam(ctx, playerID)
+				return err
+			})
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "a":
+		m.err = nil
+		m.team.creating = true
+		m.textInput.SetValue("")
+		m.textInput.Placeholder = "Team name"
+		m.textInput.CharLimit = storage.MaxTeamNameLength
+		return m, textinput.Blink
+
+	case "enter", " ":
+		if len(m.team.teams) == 0 {
+			return m, nil
+		}
+		name := m.team.teams[m.team.cursor].Name
+		return m, m.changeTeam(func(ctx context.Context, playerID int64) error {
+			_, err := m.db.JoinTeam(ctx, playerID, name)
+			return err
+		})
+	}
+
+	return m, nil
+}
+
+// handleCreateTeamInput edits the name of a new team
+func (m Model) handleCreateTeamInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.loading {
+		return m, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.err = nil
+		m = m.stopCreatingTeam()
+		return m, nil
+
+	case tea.KeyEnter:
+		name := strings.TrimSpace(m.textInput.Value())
+		if name == "" {
+			return m, nil
+		}
+		return m, m.changeTeam(func(ctx context.Context, playerID int64) error {
+			_, err := m.db.CreateTeam(ctx, playerID, name)
+			return err
+		})
+	}
+
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// stopCreatingTeam restores the text input for usernames
+func (m Model) stopCreatingTeam() Model {
+	m.team.creating = false
+	m.textInput.Placeholder = "Enter username"
+	m.textInput.CharLimit = storage.MaxUsernameLength
+	return m
+}
+
+func (m Model) handleTeamResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.state != StateTeam {
+		return m, nil
+	}
+	m.loading = false
+
+	switch msg := msg.(type) {
+	case teamMsg:
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.team.team = msg.team
+		m.team.members = msg.members
+		m.team.teams = msg.teams
+		if m.team.cursor >= len(msg.teams) {
+			m.team.cursor = max(len(msg.teams)-1, 0)
+		}
+
+	case teamChangedMsg:
+		if msg.err != nil {
+			m.err = teamError(msg.err)
+			return m, nil
+		}
+		m.err = nil
+		m = m.stopCreatingTeam()
+		return m, m.loadTeam()
+	}
+	return m, nil
+}
+
+func (m Model) renderTeam() string {
+	title := TitleStyle.Render("🤝 Team")
+
+	var rows []string
+	var footer string
+	switch {
+	case m.team.team != nil:
+		t := m.team.team
+		rows = append(rows, fmt.Sprintf("%s • %s", StatValueStyle.Render(t.Name), pluralMembers(t.Members)), "")
+		for _, member := range m.team.members {
+			best := fmt.Sprintf("%d", member.BestScore)
+			if member.Hidden {
+				best = "hidden"
+			}
+			name := truncateString(member.Username, 20)
+			if member.PlayerID == m.player.ID {
+				name += " (you)"
+			}
+			rows = append(rows, fmt.Sprintf("%-26s best %-8s joined %s", name, best, member.JoinedAt.Format("2006-01-02")))
+		}
+		footer = "X: Leave team • Esc: Back"
+
+	case m.team.creating:
+		rows = append(rows, "Name your team:", m.textInput.View())
+		footer = "Press Enter to create • Esc to cancel"
+
+	default:
+		rows = append(rows, "You aren't in a team. Join one or start your own.", "")
+		for i, t := range m.team.teams {
+			row := fmt.Sprintf("%-24s %s", truncateString(t.Name, 24), pluralMembers(t.Members))
+			if i == m.team.cursor {
+				row = LeaderboardHighlightStyle.Render("▸ " + row)
+			} else {
+				row = "  " + row
+			}
+			rows = append(rows, row)
+		}
+		if len(m.team.teams) == 0 && !m.loading && m.err == nil {
+			rows = append(rows, "No teams yet.")
+		}
+		footer = "↑/↓: Select • Enter: Join • A: Create team • Esc: Back"
+	}
+
+	if m.loading {
+		rows = append(rows, "", renderLoading())
+	}
+
+	if m.err != nil {
+		rows = append(rows, "", ErrorStyle.Render(m.err.Error()))
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, InstructionsStyle.Render(footer))
+}
+
+func pluralMembers(n int) string {
+	if n == 1 {
+		return "1 member"
+	}
+	return fmt.Sprintf("%d members", n)
+}
@@ -0,0 +1,453 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/game"
+	"github.com/rayhanadev/2048/storage"
+)
+
+const (
+	// tournamentStandingsSize is how many standings the tournament screen lists
+	tournamentStandingsSize = 10
+
+	// tournamentRefreshInterval is how often an open tournament's standings are reloaded
+	tournamentRefreshInterval = 5 * time.Second
+)
+
+type tournamentsState struct {
+	list      []storage.Tournament
+	cursor    int
+	selected  *storage.Tournament
+	progress  []storage.RoundProgress
+	standings []storage.TournamentStanding
+	round     int
+	refresh   int
+	returnTo  AppState
+}
+
+// tournamentRun is the tournament attempt being played in place of a normal game
+type tournamentRun struct {
+	attemptID  int64
+	name       string
+	round      int
+	number     int
+	attempts   int
+	normalBest int
+}
+
+// tournamentError turns storage errors from tournament play into messages suitable for players
+func tournamentError(err error) error {
+	if errors.Is(err, storage.ErrTournamentNotFound) ||
+		errors.Is(err, storage.ErrTournamentNotRunning) ||
+		errors.Is(err, storage.ErrNoAttemptsLeft) ||
+		errors.Is(err, storage.ErrAttemptClosed) {
+		return err
+	}
+	return dbError(err)
+}
+
+// tournamentsMsg carries every tournament
+type tournamentsMsg struct {
+	tournaments []storage.Tournament
+	err         error
+}
+
+// tournamentMsg carries a tournament with the player's progress and the standings
+type tournamentMsg struct {
+	tournament *storage.Tournament
+	progress   []storage.RoundProgress
+	standings  []storage.TournamentStanding
+	err        error
+}
+
+// tournamentRefreshMsg asks for the open tournament to be reloaded
+type tournamentRefreshMsg struct {
+	seq int
+}
+
+// attemptStartedMsg carries a new attempt at a tournament round
+type attemptStartedMsg struct {
+	tournament *storage.Tournament
+	attempt    *storage.TournamentAttempt
+	err        error
+}
+
+// attemptFinishedMsg reports whether a tournament attempt's score was recorded
+type attemptFinishedMsg struct {
+	round int
+	score int
+	err   error
+}
+
+func (m Model) openTournaments() (tea.Model, tea.Cmd) {
+	if m.player == nil {
+		return m, nil
+	}
+
+	m.tournaments = tournamentsState{returnTo: m.state}
+	m.err = nil
+	m.state = StateTournaments
+	return m, m.loadTournaments()
+}
+
+func (m *Model) loadTournaments() tea.Cmd {
+	m.loading = true
+	return m.query(func(ctx context.Context) tea.Msg {
+		tournaments, err := m.db.GetTournaments(ctx)
+		return tournamentsMsg{tournaments: tournaments, err: err}
+	})
+}
+
+// loadTournament reloads the selected tournament without showing a loading
+// indicator when refreshing standings in the background
+func (m *Model) loadTournament(background bool) tea.Cmd {
+	if !background {
+		m.loading = true
+	}
+	id := m.tournaments.selected.ID
+	playerID := m.player.ID
+	return m.query(func(ctx context.Context) tea.Msg {
+		t, err := m.db.GetTournament(ctx, id)
+		if err != nil {
+			return tournamentMsg{err: err}
+		}
+		msg := tournamentMsg{tournament: t}
+		msg.progress, msg.err = m.db.GetRoundProgress(ctx, t, playerID)
+		if msg.err != nil {
+			return msg
+		}
+		msg.standings, msg.err = m.db.GetTournamentStandings(ctx, t, tournamentStandingsSize)
+		return msg
+	})
+}
+
+func tournamentRefreshCmd(seq int) tea.Cmd {
+	return tea.Tick(tournamentRefreshInterval, func(time.Time) tea.Msg {
+		return tournamentRefreshMsg{seq: seq}
+	})
+}
+
+func (m Model) handleTournamentsInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.tournaments.selected != nil {
+		return m.handleTournamentInput(msg)
+	}
+
+	switch msg.String() {
+	case "esc", "p":
+		m.err = nil
+		m.loading = false
+		m.state = m.tournaments.returnTo
+		return m, nil
+
+	case "up", "k":
+		if m.tournaments.cursor > 0 {
+			m.tournaments.cursor--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.tournaments.cursor < len(m.tournaments.list)-1 {
+			m.tournaments.cursor++
+		}
+		return m, nil
+
+	case "enter", " ":
+		if m.loading || len(m.tournaments.list) == 0 {
+			return m, nil
+		}
+		t := m.tournaments.list[m.tournaments.cursor]
+		m.tournaments.selected = &t
+		m.tournaments.progress = nil
+		m.tournaments.standings = nil
+		m.tournaments.round = 0
+		m.tournaments.refresh++
+		m.err = nil
+		return m, tea.Batch(m.loadTournament(false), tournamentRefreshCmd(m.tournaments.refresh))
+	}
+
+	return m, nil
+}
+
+// handleTournamentInput handles keys on a single tournament's screen
+func (m Model) handleTournamentInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.err = nil
+		m.tournaments.selected = nil
+		m.tournaments.refresh++
+		return m, m.loadTournaments()
+
+	case "p":
+		m.err = nil
+		m.loading = false
+		m.tournaments.refresh++
+		m.state = m.tournaments.returnTo
+		return m, nil
+
+	case "up", "k":
+		if m.tournaments.round > 0 {
+			m.tournaments.round--
+		}
+		return m, nil
+
+	case "down", "j":
+		if m.tournaments.round < m.tournaments.selected.Rounds()-1 {
+			m.tournaments.round++
+		}
+		return m, nil
+
+	case "enter", " ":
+		if m.loading {
+			return m, nil
+		}
+		m.loading = true
+		m.err = nil
+		t := m.tournaments.selected
+		round := m.tournaments.round + 1
+		playerID := m.player.ID
+		return m, m.query(func(ctx context.Context) tea.Msg {
+			attempt, err := m.db.StartTournamentAttempt(ctx, t.ID, round, playerID)
+			return attemptStartedMsg{tournament: t, attempt: attempt, err: err}
+		})
+	}
+
+	return m, nil
+}
+
+// finishAttempt records the current game as the score of the tournament attempt
+func (m Model) finishAttempt() tea.Cmd {
+	run := *m.run
+	playerID := m.player.ID
+	score, maxTile, moves := m.game.Score, m.game.MaxTile(), m.game.Moves
+	return m.query(func(ctx context.Context) tea.Msg {
+		err := m.db.FinishTournamentAttempt(ctx, run.attemptID, playerID, score, maxTile, moves)
+		return attemptFinishedMsg{round: run.round, score: score, err: err}
+	})
+}
+
+// leaveTournamentRun ends the tournament attempt, scoring it if it is still
+// being played, and starts a normal game
+func (m Model) leaveTournamentRun() (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if !m.game.GameOver {
+		cmd = m.finishAttempt()
+	}
+	m.game = game.NewGame(m.run.normalBest)
+	m.run = nil
+	m.achievements.NewGame()
+	m.state = StatePlaying
+	return m, cmd
+}
+
+func (m Model) handleTournamentsResult(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case attemptFinishedMsg:
+		if msg.err != nil {
+			m.logger().Error("Failed to record tournament attempt", "error", msg.err)
+			return m, m.showToast("⚠️  Couldn't record your round: " + tournamentError(msg.err).Error())
+		}
+		return m, m.showToast(fmt.Sprintf("🏟  Round %d scored %d", msg.round, msg.score))
+
+	case tournamentRefreshMsg:
+		if m.state != StateTournaments || m.tournaments.selected == nil || msg.seq != m.tournaments.refresh {
+			return m, nil
+		}
+		if m.loading || m.tournaments.selected.Finalized() {
+			return m, tournamentRefreshCmd(msg.seq)
+		}
+		return m, tea.Batch(m.loadTournament(true), tournamentRefreshCmd(msg.seq))
+	}
+
+	if m.state != StateTournaments {
+		return m, nil
+	}
+	m.loading = false
+
+	switch msg := msg.(type) {
+	case tournamentsMsg:
+		if msg.err != nil {
+			m.err = dbError(msg.err)
+			return m, nil
+		}
+		m.tournaments.list = msg.tournaments
+		if m.tournaments.cursor >= len(msg.tournaments) {
+			m.tournaments.cursor = max(len(msg.tournaments)-1, 0)
+		}
+
+	case tournamentMsg:
+		if m.tournaments.selected == nil {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = tournamentError(msg.err)
+			return m, nil
+		}
+		if msg.tournament.ID != m.tournaments.selected.ID {
+			return m, nil
+		}
+		m.err = nil
+		m.tournaments.selected = msg.tournament
+		m.tournaments.progress = msg.progress
+		m.tournaments.standings = msg.standings
+
+	case attemptStartedMsg:
+		if msg.err != nil {
+			m.err = tournamentError(msg.err)
+			return m, nil
+		}
+		normalBest := m.game.BestScore
+		if m.run != nil {
+			normalBest = m.run.normalBest
+		}
+		roundBest := 0
+		if i := msg.attempt.Round - 1; i < len(m.tournaments.progress) {
+			roundBest = m.tournaments.progress[i].Best
+		}
+
+		m.run = &tournamentRun{
+			attemptID:  msg.attempt.ID,
+			name:       msg.tournament.Name,
+			round:      msg.attempt.Round,
+			number:     msg.attempt.Number,
+			attempts:   msg.tournament.Attempts,
+			normalBest: normalBest,
+		}
+		m.game = game.NewSeededGame(msg.attempt.Seed, roundBest)
+		m.achievements.NewGame()
+		m.tournaments.refresh++
+		m.err = nil
+		m.state = StatePlaying
+	}
+	return m, nil
+}
+
+func (m Model) renderTournaments() string {
+	if m.tournaments.selected != nil {
+		return m.renderTournament()
+	}
+
+	title := TitleStyle.Render("🏟  Tournaments")
+
+	now := time.Now()
+	var rows []string
+	for i, t := range m.tournaments.list {
+		row := fmt.Sprintf("%-20s %-10s %s", truncateString(t.Name, 20), t.Status(now), tournamentFormat(&t))
+		if i == m.tournaments.cursor {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+
+	switch {
+	case m.loading:
+		rows = append(rows, renderLoading())
+	case m.err != nil:
+		rows = append(rows, ErrorStyle.Render(m.err.Error()))
+	case len(m.tournaments.list) == 0:
+		rows = append(rows, "No tournaments yet.")
+	}
+
+	return renderTournamentBox(title, rows, "↑/↓: Select • Enter: Open • Esc: Back")
+}
+
+// renderTournament shows the rounds of the selected tournament and its standings
+func (m Model) renderTournament() string {
+	t := m.tournaments.selected
+	title := TitleStyle.Render("🏟  " + t.Name)
+
+	now := time.Now()
+	var when string
+	switch t.Status(now) {
+	case "upcoming", "opening":
+		when = "Starts in " + formatDuration(time.Until(t.StartsAt))
+	case "running":
+		when = "Ends in " + formatDuration(time.Until(t.EndsAt))
+	case "closing":
+		when = "Ended • Results are being finalized"
+	default:
+		when = "Final standings"
+	}
+
+	rows := []string{StatLabelStyle.UnsetWidth().Render(tournamentFormat(t) + " • " + when), ""}
+
+	for i, p := range m.tournaments.progress {
+		row := fmt.Sprintf("Round %-3d %d/%d attempts used • best %d", p.Round, p.Used, t.Attempts, p.Best)
+		if i == m.tournaments.round {
+			row = LeaderboardHighlightStyle.Render("▸ " + row)
+		} else {
+			row = "  " + row
+		}
+		rows = append(rows, row)
+	}
+
+	headerRow := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(fmt.Sprintf("%-4s %-15s %-8s %s", "Rank", "Player", "Total", "Rounds"))
+	rows = append(rows, "", headerRow, strings.Repeat("─", 44))
+
+	for _, s := range m.tournaments.standings {
+		rounds := make([]string, len(s.RoundScores))
+		for i, score := range s.RoundScores {
+			rounds[i] = strconv.Itoa(score)
+		}
+		row := fmt.Sprintf("%-4d %-15s %-8d %s", s.Rank, truncateString(s.Username, 15), s.Total, strings.Join(rounds, "/"))
+		if s.PlayerID == m.player.ID {
+			row = LeaderboardHighlightStyle.Render(row)
+		}
+		rows = append(rows, row)
+	}
+
+	switch {
+	case m.loading:
+		rows = append(rows, renderLoading())
+	case m.err != nil:
+		rows = append(rows, ErrorStyle.Render(m.err.Error()))
+	case len(m.tournaments.standings) == 0:
+		rows = append(rows, "No scores yet!")
+	}
+
+	footer := "Esc: All tournaments • P: Back to game"
+	if t.Running(now) {
+		footer = "↑/↓: Round • Enter: Play round • " + footer
+	}
+	return renderTournamentBox(title, rows, footer)
+}
+
+func renderTournamentBox(title string, rows []string, footer string) string {
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, InstructionsStyle.Render(footer))
+}
+
+// tournamentFormat describes a tournament's rounds and attempts
+func tournamentFormat(t *storage.Tournament) string {
+	rounds := "1 round"
+	if t.Rounds() != 1 {
+		rounds = fmt.Sprintf("%d rounds", t.Rounds())
+	}
+	attempts := "1 attempt"
+	if t.Attempts != 1 {
+		attempts = fmt.Sprintf("%d attempts", t.Attempts)
+	}
+	return rounds + " × " + attempts
+}
@@ -3,8 +3,11 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rayhanadev/2048/storage"
 )
 
 func (m Model) renderUsernameEntry() string {
@@ -12,15 +15,27 @@ func (m Model) renderUsernameEntry() string {
 
 	var content strings.Builder
 	content.WriteString("\n\n")
-	content.WriteString("This appears to be your first time playing.\n")
-	content.WriteString("Please enter a username (3-20 characters):\n\n")
+	if m.linking {
+		content.WriteString("Link this key to an existing account.\n")
+		content.WriteString("Get a pairing code from the Keys screen (U) in another session:\n\n")
+	} else {
+		content.WriteString("This appears to be your first time playing.\n")
+		content.WriteString("Please enter a username (3-20 letters, numbers, '_', '-' or '.'):\n\n")
+	}
 	content.WriteString(m.textInput.View())
 	content.WriteString("\n\n")
-	content.WriteString("Press Enter to continue")
+	if m.linking {
+		content.WriteString("Press Enter to link • Tab to create a new account instead")
+	} else {
+		content.WriteString("Press Enter to continue • Tab to link an existing account")
+	}
 
-	if m.err != nil {
+	if m.loading {
+		content.WriteString("\n\n")
+		content.WriteString(renderLoading())
+	} else if m.err != nil {
 		content.WriteString("\n\n")
-		content.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Render(m.err.Error()))
+		content.WriteString(ErrorStyle.Render(m.err.Error()))
 	}
 
 	box := lipgloss.NewStyle().
@@ -37,7 +52,7 @@ func (m Model) renderGame() string {
 	board := m.renderBoard()
 	footer := m.renderFooter()
 
-	return lipgloss.JoinVertical(lipgloss.Center, header, board, footer)
+	return lipgloss.JoinVertical(lipgloss.Center, m.renderToasts(), header, board, footer)
 }
 
 func (m Model) renderGameOver() string {
@@ -51,48 +66,136 @@ func (m Model) renderGameOver() string {
 		msg = GameOverStyle.Render("Game Over!")
 	}
 
-	instructions := InstructionsStyle.Render("Press R to restart • B for leaderboard • Q to quit")
+	restart := "Press R to restart"
+	if m.run != nil {
+		restart = "Press R for a normal game"
+	}
+	instructions := InstructionsStyle.Render(restart + " • Q to quit\n" + m.menuKeys())
 
-	return lipgloss.JoinVertical(lipgloss.Center, header, board, msg, instructions)
+	if m.guest {
+		return lipgloss.JoinVertical(lipgloss.Center, m.renderToasts(), header, board, msg, m.renderClaim(), instructions)
+	}
+	return lipgloss.JoinVertical(lipgloss.Center, m.renderToasts(), header, board, msg, instructions)
 }
 
 func (m Model) renderLeaderboard() string {
-	title := TitleStyle.Render("🏆 Top 10 Leaderboard 🏆")
+	title := TitleStyle.Render(fmt.Sprintf("🏆 Top %d Leaderboard 🏆", leaderboardSize))
+
+	seasonLine := "All time"
+	if season := m.selectedSeason(); season != nil {
+		dates := fmt.Sprintf("%s – %s", season.StartsAt.Format("Jan 2"), season.EndsAt.Format("Jan 2, 2006"))
+		switch {
+		case season.Archived():
+			seasonLine = fmt.Sprintf("%s (%s) • Final standings", season.Name, dates)
+		case season.Active(time.Now()):
+			left := time.Until(season.EndsAt)
+			remaining := formatDuration(left)
+			if left >= 24*time.Hour {
+				remaining = pluralDays(int(left.Hours() / 24))
+			}
+			seasonLine = fmt.Sprintf("%s (%s) • Ends in %s", season.Name, dates, remaining)
+		default:
+			seasonLine = fmt.Sprintf("%s (%s) • Ended", season.Name, dates)
+		}
+	}
+
+	tabNames := []string{"Global", "Friends", "Teams"}
+	for i, name := range tabNames {
+		if leaderboardTab(i) == m.tab {
+			tabNames[i] = LeaderboardHighlightStyle.Render(name)
+		}
+	}
 
 	var rows []string
+	rows = append(rows, strings.Join(tabNames, " • "), StatLabelStyle.Render(seasonLine), "")
+	if m.tab == tabTeams {
+		rows = append(rows, m.renderTeamStandings()...)
+	} else {
+		rows = append(rows, m.renderLeaderboardEntries()...)
+	}
+
+	content := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(strings.Join(rows, "\n"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3d3d5c")).
+		Padding(1, 2).
+		Render(content)
+
+	footer := "Tab: Global/Friends/Teams • Press Enter or B to return"
+	if m.tab == tabTeams {
+		footer = "S: Sum/Average • " + footer
+	}
+	if len(m.seasons) > 0 {
+		footer = "←/→: Season • " + footer
+	}
+	footer = InstructionsStyle.Render(footer)
+
+	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+}
+
+func (m Model) renderLeaderboardEntries() []string {
 	headerRow := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("#f9f6f2")).
 		Render(fmt.Sprintf("%-4s %-15s %-8s %-6s", "Rank", "Player", "Score", "Tile"))
-	rows = append(rows, headerRow)
-	rows = append(rows, strings.Repeat("─", 40))
+	rows := []string{headerRow, strings.Repeat("─", 40)}
 
-	for i, entry := range m.leaderboard {
+	for _, entry := range m.leaderboard {
 		row := fmt.Sprintf("%-4d %-15s %-8d %-6d",
-			i+1,
+			entry.Rank,
 			truncateString(entry.Username, 15),
 			entry.Score,
 			entry.MaxTile)
 		rows = append(rows, row)
 	}
 
-	if len(m.leaderboard) == 0 {
+	switch {
+	case m.loading:
+		rows = append(rows, renderLoading())
+	case m.err != nil:
+		rows = append(rows, ErrorStyle.Render(m.err.Error()))
+	case len(m.leaderboard) == 0 && m.tab == tabFriends:
+		rows = append(rows, "No scores yet! Follow players from the Friends screen (F).")
+	case len(m.leaderboard) == 0:
 		rows = append(rows, "No scores yet!")
 	}
 
-	content := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#f9f6f2")).
-		Render(strings.Join(rows, "\n"))
+	return rows
+}
 
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#3d3d5c")).
-		Padding(1, 2).
-		Render(content)
+func (m Model) renderTeamStandings() []string {
+	scoreLabel := "Total"
+	if m.teamScoring == storage.TeamScoringAverage {
+		scoreLabel = "Average"
+	}
 
-	footer := InstructionsStyle.Render("Press Enter or B to return")
+	headerRow := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#f9f6f2")).
+		Render(fmt.Sprintf("%-4s %-20s %-8s %-7s", "Rank", "Team", scoreLabel, "Players"))
+	rows := []string{headerRow, strings.Repeat("─", 42)}
+
+	for _, st := range m.teamStandings {
+		rows = append(rows, fmt.Sprintf("%-4d %-20s %-8d %-7d",
+			st.Rank,
+			truncateString(st.Name, 20),
+			st.Score,
+			st.Members))
+	}
 
-	return lipgloss.JoinVertical(lipgloss.Center, title, box, footer)
+	switch {
+	case m.loading:
+		rows = append(rows, renderLoading())
+	case m.err != nil:
+		rows = append(rows, ErrorStyle.Render(m.err.Error()))
+	case len(m.teamStandings) == 0:
+		rows = append(rows, "No team scores yet! Join a team from the Team screen (M).")
+	}
+
+	return rows
 }
 
 func (m Model) renderHeader() string {
@@ -133,6 +236,12 @@ func (m Model) renderHeader() string {
 
 	scores := lipgloss.JoinHorizontal(lipgloss.Center, scoreBox, "  ", bestBox)
 
+	if m.run != nil {
+		runInfo := LeaderboardHighlightStyle.Render(fmt.Sprintf("🏟  %s • Round %d • Attempt %d/%d",
+			m.run.name, m.run.round, m.run.number, m.run.attempts))
+		return lipgloss.JoinVertical(lipgloss.Center, playerInfo, runInfo, "", scores, "")
+	}
+
 	return lipgloss.JoinVertical(lipgloss.Center, playerInfo, "", scores, "")
 }
 
@@ -240,14 +349,33 @@ func (m Model) renderTile(value int) string {
 	return style.Render(strings.Join(lines, "\n"))
 }
 
+// menuKeys lists the screens reachable from the game and game over views
+const menuKeys = "B: Leaderboard • T: Stats • C: Achievements • N: Rename • U: Keys • F: Friends • M: Team • P: Tournaments • O: Settings"
+
+// menuKeys lists the screens this player can reach: fewer for guests, and
+// the admin screen for admins
+func (m Model) menuKeys() string {
+	if m.guest {
+		return guestMenuKeys
+	}
+	if m.admin != nil {
+		return menuKeys + " • Shift+A: Admin"
+	}
+	return menuKeys
+}
+
 func (m Model) renderFooter() string {
-	instructions := "↑/↓/←/→: Move • R: Restart • B: Leaderboard • Q: Quit"
+	instructions := "↑/↓/←/→: Move • R: Restart • Q: Quit\n" + m.menuKeys()
+	if m.run != nil {
+		instructions = "↑/↓/←/→: Move • R: End round with this score • Q: Quit\n" + m.menuKeys()
+	}
 	return InstructionsStyle.Render(instructions)
 }
 
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }